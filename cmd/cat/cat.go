@@ -0,0 +1,172 @@
+package cat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// catOptions holds the configuration for the cat command
+type catOptions struct {
+	// list prints the file tree of the artifact instead of the content of a file.
+	list bool
+}
+
+var opts = &catOptions{}
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat [flags] <ref> <path-in-artifact>",
+	Short: "Print a single file of an artifact",
+	Long: `Print a single file of an artifact to stdout, without downloading the whole artifact to disk.
+
+Paths are the ones the download command extracts the artifact to: the title of a file layer, or
+the path of an entry of a directory layer (e.g., results/junit.xml). Only the layers up to the one
+holding the file are read.
+
+Examples:
+  - Print the JUnit report of an artifact:
+      konflux-oci-artifacts cat quay.io/org/repo:tag results/junit.xml
+
+  - Show the file tree of an artifact:
+      konflux-oci-artifacts cat quay.io/org/repo:tag --list`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if opts.list {
+			return cobra.RangeArgs(1, 2)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Layers are streamed without being stored, so the store of the controller lives in a throwaway directory
+		storeDir, err := os.MkdirTemp("", "konflux-oci-cat-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(storeDir)
+
+		// Streaming has no deadline, an interrupt cancels it and still removes the store
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		ociController, err := oci.NewController(storeDir, storeDir)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		repo, reference, err := ociController.OpenReference(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		if opts.list {
+			files, err := ociController.ListFiles(ctx, repo, reference)
+			if err != nil {
+				return err
+			}
+			prefix := ""
+			if len(args) == 2 {
+				prefix = strings.Trim(args[1], "/")
+			}
+			return printTree(cmd.OutOrStdout(), files, prefix)
+		}
+
+		out := bufio.NewWriter(cmd.OutOrStdout())
+		if err := ociController.CatFile(ctx, repo, reference, args[1], out); err != nil {
+			return err
+		}
+		return out.Flush()
+	},
+}
+
+// Init initializes the cat command and its flags
+func Init() *cobra.Command {
+	catCmd.Flags().BoolVar(&opts.list, "list", false, "Print the file tree of the artifact, optionally below a path, instead of a file")
+
+	return catCmd
+}
+
+// treeNode is a directory or file of the printed tree.
+type treeNode struct {
+	name     string
+	size     int64
+	dir      bool
+	children map[string]*treeNode
+}
+
+// printTree prints the files below the prefix as a tree, with the size of every file.
+func printTree(w io.Writer, files []oci.ArtifactFile, prefix string) error {
+	root := &treeNode{name: ".", dir: true, children: map[string]*treeNode{}}
+	if prefix != "" {
+		root.name = prefix
+	}
+
+	for _, file := range files {
+		rel := file.Path
+		if prefix != "" {
+			if rel != prefix && !strings.HasPrefix(rel, prefix+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(strings.TrimPrefix(rel, prefix), "/")
+		}
+		if rel == "" {
+			continue
+		}
+
+		node := root
+		parts := strings.Split(rel, "/")
+		for i, part := range parts {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, dir: true, children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			if i == len(parts)-1 {
+				child.dir, child.size = file.Dir, file.Size
+			}
+			node = child
+		}
+	}
+
+	if prefix != "" && len(root.children) == 0 {
+		return fmt.Errorf("%w: %s", oci.ErrFileNotFound, prefix)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, root.name)
+	writeTreeChildren(bw, root, "")
+	return bw.Flush()
+}
+
+// writeTreeChildren writes the children of a node sorted by name, with directories marked by a slash.
+func writeTreeChildren(w io.Writer, node *treeNode, indent string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := node.children[name]
+		branch, nextIndent := "├── ", indent+"│   "
+		if i == len(names)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+		if child.dir {
+			fmt.Fprintf(w, "%s%s%s/\n", indent, branch, child.name)
+			writeTreeChildren(w, child, nextIndent)
+		} else {
+			fmt.Fprintf(w, "%s%s%s (%s)\n", indent, branch, child.name, progress.FormatBytes(child.size))
+		}
+	}
+}
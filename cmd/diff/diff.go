@@ -0,0 +1,302 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	artifactdiff "github.com/flacatus/oras-puller/pkg/diff"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+// Supported formats for the --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// maxTextDiffSize is the size above which no unified diff is computed for a file.
+const maxTextDiffSize = 1 << 20
+
+// diffOptions holds the configuration for the diff command
+type diffOptions struct {
+	// unified adds the unified diff of the text files that differ.
+	unified bool
+
+	// context is the number of context lines of the unified diffs.
+	context int
+
+	// ociCache is the directory of the OCI store the artifacts are downloaded to.
+	ociCache string
+
+	// output selects the output format: text or json.
+	output string
+}
+
+var opts = &diffOptions{}
+
+// artifact is one of the two compared artifacts, downloaded and extracted.
+type artifact struct {
+	reference  string
+	inspection *oci.Inspection
+	dir        string
+}
+
+// report is the comparison of two artifacts printed by the diff command.
+type report struct {
+	From        string                          `json:"from"`
+	FromDigest  string                          `json:"fromDigest"`
+	To          string                          `json:"to"`
+	ToDigest    string                          `json:"toDigest"`
+	Annotations []artifactdiff.AnnotationChange `json:"annotations"`
+	Layers      []artifactdiff.LayerChange      `json:"layers"`
+	Files       []artifactdiff.FileChange       `json:"files"`
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags] <refA> <refB>",
+	Short: "Compare the manifests and files of two artifacts",
+	Long: `Compare two artifacts: the annotations and layers of their manifests, and the files they are
+extracted to by the download command. Files are compared by size and SHA-256 hash.
+
+The artifacts are downloaded through the OCI cache and extracted into a temporary directory. References
+are registry references (quay.io/org/repo:tag) or tags of OCI image layouts and archives.
+
+Examples:
+  - Compare the artifacts of two nightly runs:
+      konflux-oci-artifacts diff quay.io/org/repo:nightly-1 quay.io/org/repo:nightly-2
+
+  - Show the changed lines of the text files:
+      konflux-oci-artifacts diff quay.io/org/repo:nightly-1 quay.io/org/repo:nightly-2 --unified`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if opts.output != outputText && opts.output != outputJSON {
+			return fmt.Errorf("unsupported output format %q (expected %s or %s)", opts.output, outputText, outputJSON)
+		}
+		if opts.context < 0 {
+			return fmt.Errorf("--context must not be negative")
+		}
+
+		if opts.ociCache == "" {
+			var err error
+			if opts.ociCache, err = oci.DefaultCacheDir(); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(opts.ociCache, os.ModePerm); err != nil {
+			return fmt.Errorf("could not create cache directory: %v", err)
+		}
+
+		tempDir, err := os.MkdirTemp("", "konflux-oci-diff-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		from, err := fetchArtifact(cmd, args[0], filepath.Join(tempDir, "a"))
+		if err != nil {
+			return err
+		}
+		to, err := fetchArtifact(cmd, args[1], filepath.Join(tempDir, "b"))
+		if err != nil {
+			return err
+		}
+
+		result, err := compare(from, to)
+		if err != nil {
+			return err
+		}
+
+		if opts.output == outputJSON {
+			if result.Annotations == nil {
+				result.Annotations = []artifactdiff.AnnotationChange{}
+			}
+			if result.Layers == nil {
+				result.Layers = []artifactdiff.LayerChange{}
+			}
+			if result.Files == nil {
+				result.Files = []artifactdiff.FileChange{}
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		}
+		return printReport(cmd.OutOrStdout(), result)
+	},
+}
+
+// Init initializes the diff command and its flags
+func Init() *cobra.Command {
+	diffCmd.Flags().BoolVarP(&opts.unified, "unified", "u", false, "Show the unified diff of the text files that differ")
+	diffCmd.Flags().IntVar(&opts.context, "context", 3, "Number of context lines of the unified diffs")
+	diffCmd.Flags().StringVar(&opts.ociCache, "oci-cache", "", "Path to the OCI cache directory (default: $HOME/.config/konflux-oci-artifacts/cache)")
+	diffCmd.Flags().StringVarP(&opts.output, "output", "o", outputText, "Output format: text or json")
+
+	return diffCmd
+}
+
+// fetchArtifact downloads the artifact through the cache and extracts it below outputDir.
+func fetchArtifact(cmd *cobra.Command, reference, outputDir string) (*artifact, error) {
+	ociController, err := oci.NewController(outputDir, opts.ociCache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCI controller: %w", err)
+	}
+	ociController.Logger = slog.Default()
+
+	repo, tag, err := ociController.OpenReference(cmd.Context(), reference)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection, err := ociController.Inspect(repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	creationDate := time.Now().Format(time.RFC1123)
+	tagPlan, err := ociController.PlanTag(repo, tag, creationDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := ociController.ProcessTag(repo, tag, creationDate); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", reference, err)
+	}
+
+	return &artifact{reference: reference, inspection: inspection, dir: tagPlan.OutputDir}, nil
+}
+
+// compare builds the report of the differences between two artifacts.
+func compare(from, to *artifact) (*report, error) {
+	files, err := artifactdiff.CompareTrees(from.dir, to.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.unified {
+		for i := range files {
+			files[i].Diff = unifiedDiff(from.dir, to.dir, files[i])
+		}
+	}
+
+	return &report{
+		From:        from.reference,
+		FromDigest:  from.inspection.Descriptor.Digest.String(),
+		To:          to.reference,
+		ToDigest:    to.inspection.Descriptor.Digest.String(),
+		Annotations: artifactdiff.CompareAnnotations(from.inspection.Annotations, to.inspection.Annotations),
+		Layers:      artifactdiff.CompareLayers(from.inspection.Manifest.Layers, to.inspection.Manifest.Layers),
+		Files:       files,
+	}, nil
+}
+
+// unifiedDiff returns the unified diff of a changed file, or an empty string when one of its
+// versions is not text or is too large. An added or removed file is diffed against an empty file.
+func unifiedDiff(fromDir, toDir string, change artifactdiff.FileChange) string {
+	if change.OldSize > maxTextDiffSize || change.NewSize > maxTextDiffSize {
+		return ""
+	}
+
+	oldName, newName := "a/"+change.Path, "b/"+change.Path
+	var old, new []byte
+	var err error
+	if change.Status != artifactdiff.StatusAdded {
+		if old, err = os.ReadFile(filepath.Join(fromDir, filepath.FromSlash(change.Path))); err != nil {
+			return ""
+		}
+	} else {
+		oldName = "/dev/null"
+	}
+	if change.Status != artifactdiff.StatusRemoved {
+		if new, err = os.ReadFile(filepath.Join(toDir, filepath.FromSlash(change.Path))); err != nil {
+			return ""
+		}
+	} else {
+		newName = "/dev/null"
+	}
+	if !artifactdiff.IsText(old) || !artifactdiff.IsText(new) {
+		return ""
+	}
+
+	unified, err := artifactdiff.Unified(oldName, newName, old, new, opts.context)
+	if err != nil {
+		slog.Warn("Skipping unified diff", "path", change.Path, "error", err)
+		return ""
+	}
+	return unified
+}
+
+// printReport writes a human-readable report of the differences to w.
+func printReport(w io.Writer, result *report) error {
+	fmt.Fprintf(w, "--- %s (%s)\n+++ %s (%s)\n", result.From, result.FromDigest, result.To, result.ToDigest)
+	if len(result.Annotations) == 0 && len(result.Layers) == 0 && len(result.Files) == 0 {
+		_, err := fmt.Fprintln(w, "\nNo differences")
+		return err
+	}
+
+	if len(result.Annotations) > 0 {
+		fmt.Fprintf(w, "\nAnnotations (%d):\n", len(result.Annotations))
+		for _, change := range result.Annotations {
+			switch change.Status {
+			case artifactdiff.StatusAdded:
+				fmt.Fprintf(w, "  + %s: %s\n", change.Key, change.New)
+			case artifactdiff.StatusRemoved:
+				fmt.Fprintf(w, "  - %s: %s\n", change.Key, change.Old)
+			default:
+				fmt.Fprintf(w, "  ~ %s: %s -> %s\n", change.Key, change.Old, change.New)
+			}
+		}
+	}
+
+	if len(result.Layers) > 0 {
+		fmt.Fprintf(w, "\nLayers (%d):\n", len(result.Layers))
+		for _, change := range result.Layers {
+			marker := "+"
+			if change.Status == artifactdiff.StatusRemoved {
+				marker = "-"
+			}
+			title := change.Title
+			if title == "" {
+				title = "-"
+			}
+			fmt.Fprintf(w, "  %s %s %s (%s)\n", marker, change.Digest, title, progress.FormatBytes(change.Size))
+		}
+	}
+
+	if len(result.Files) > 0 {
+		fmt.Fprintf(w, "\nFiles (%d):\n", len(result.Files))
+		for _, change := range result.Files {
+			switch change.Status {
+			case artifactdiff.StatusAdded:
+				fmt.Fprintf(w, "  + %s (%s)\n", change.Path, progress.FormatBytes(change.NewSize))
+			case artifactdiff.StatusRemoved:
+				fmt.Fprintf(w, "  - %s (%s)\n", change.Path, progress.FormatBytes(change.OldSize))
+			default:
+				fmt.Fprintf(w, "  ~ %s (%s -> %s, %s -> %s)\n", change.Path, progress.FormatBytes(change.OldSize),
+					progress.FormatBytes(change.NewSize), shortHash(change.OldHash), shortHash(change.NewHash))
+			}
+		}
+	}
+
+	for _, change := range result.Files {
+		if change.Diff != "" {
+			fmt.Fprintf(w, "\n%s", change.Diff)
+		}
+	}
+	return nil
+}
+
+// shortHash shortens a sha256:<hex> hash to its first 12 hexadecimal characters.
+func shortHash(hash string) string {
+	const prefix = len("sha256:")
+	if len(hash) > prefix+12 {
+		return hash[prefix : prefix+12]
+	}
+	return hash
+}
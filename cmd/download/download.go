@@ -1,15 +1,20 @@
 package download
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/flacatus/oras-puller/pkg/timeutil"
 	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2/registry"
 )
 
 // downloadOptions holds the configuration options for the download command.
@@ -40,6 +45,26 @@ type downloadOptions struct {
 	// noCache determines whether to remove the OCI cache after downloading artifacts.
 	// If true, the cache will be deleted after the command execution completes, regardless of success or failure.
 	noCache bool
+
+	// progress selects how download progress is reported: auto, tty, json or none.
+	// In auto mode live progress is rendered when stderr is a terminal.
+	progress string
+
+	// maxTotalSize is the budget for the compressed size of all downloaded artifacts (e.g., "10GiB").
+	// The download aborts before fetching any layer when the planned size exceeds it.
+	maxTotalSize string
+
+	// dryRun resolves the tags and manifests only and prints what a real run would download.
+	dryRun bool
+
+	// output selects the format of the dry run report: table or json.
+	output string
+
+	// referrersOf is an image reference (e.g., quay.io/org/image@sha256:...) whose attached artifacts are downloaded.
+	referrersOf string
+
+	// artifactType restricts the downloaded referrers to this artifact type.
+	artifactType string
 }
 
 var opts = &downloadOptions{}
@@ -58,13 +83,19 @@ Examples:
       konflux-oci-artifacts download --repos quay.io/repo1 quay.io/repo2 --since 4h --artifacts-output /path/to/output
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-
 		// Validation: Fail if both 'repo' and 'repos' are provided
 		if opts.repo != "" && len(opts.repos) > 0 {
 			return fmt.Errorf("you cannot use both --repo and --repos at the same time")
 		}
 
+		// Validation: referrers are downloaded on their own
+		if opts.referrersOf != "" && (opts.repo != "" || len(opts.repos) > 0) {
+			return fmt.Errorf("you cannot use --referrers-of together with --repo or --repos")
+		}
+		if opts.artifactType != "" && opts.referrersOf == "" {
+			return fmt.Errorf("the --artifact-type flag requires the --referrers-of flag")
+		}
+
 		// Validation: Fail if 'since' is provided without 'repos', or 'repos' is provided without 'since'
 		if opts.since != "" && len(opts.repos) == 0 {
 			return fmt.Errorf("the --since flag requires the --repos flag")
@@ -74,9 +105,9 @@ Examples:
 		}
 
 		// If neither 'repo' nor 'repos' is provided, show command-specific help
-		if opts.repo == "" && len(opts.repos) == 0 {
+		if opts.repo == "" && len(opts.repos) == 0 && opts.referrersOf == "" {
 			cmd.Help()
-			return fmt.Errorf("either --repo or --repos must be specified")
+			return fmt.Errorf("either --repo, --repos or --referrers-of must be specified")
 		}
 
 		// Check if the mandatory artifactsOutput flag is provided
@@ -84,78 +115,190 @@ Examples:
 			return fmt.Errorf("the --artifacts-output flag is mandatory")
 		}
 
+		// Validation: the output format only applies to the plan printed by a dry run
+		if cmd.Flags().Changed("output") && !opts.dryRun {
+			return fmt.Errorf("the --output flag requires the --dry-run flag")
+		}
+		if opts.output != outputTable && opts.output != outputJSON {
+			return fmt.Errorf("unsupported output format %q (expected %s or %s)", opts.output, outputTable, outputJSON)
+		}
+
+		reporter, err := progress.New(opts.progress, os.Stderr)
+		if err != nil {
+			return fmt.Errorf("invalid value for --progress: %v", err)
+		}
+
 		// Set the default OCI cache directory if not specified
 		if opts.ociCache == "" {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("could not determine home directory: %v", err)
+			if opts.ociCache, err = oci.DefaultCacheDir(); err != nil {
+				return err
 			}
-			opts.ociCache = filepath.Join(homeDir, ".config", "konflux-oci-artifacts", "cache")
-		}
-
-		// Create the cache directory if it doesn't exist
-		if err := os.MkdirAll(opts.ociCache, os.ModePerm); err != nil {
-			return fmt.Errorf("could not create cache directory: %v", err)
 		}
 
-		// Use defer to ensure cache removal at the end
-		defer func() {
-			if opts.noCache {
-				if err := os.RemoveAll(opts.ociCache); err != nil {
-					fmt.Printf("Warning: could not remove cache directory: %v\n", err)
+		if opts.dryRun {
+			// A dry run leaves the filesystem untouched: an existing cache is only read to report
+			// the cached layers, and a missing one is replaced with an empty temporary store
+			if _, err := os.Stat(opts.ociCache); err != nil {
+				if opts.ociCache, err = os.MkdirTemp("", "konflux-oci-dry-run-"); err != nil {
+					return fmt.Errorf("failed to create temporary directory: %w", err)
 				}
+				defer os.RemoveAll(opts.ociCache)
+			}
+		} else {
+			// Create the cache directory if it doesn't exist
+			if err := os.MkdirAll(opts.ociCache, os.ModePerm); err != nil {
+				return fmt.Errorf("could not create cache directory: %v", err)
 			}
-		}()
+
+			// Use defer to ensure cache removal at the end
+			defer func() {
+				if opts.noCache {
+					if err := os.RemoveAll(opts.ociCache); err != nil {
+						slog.Warn("Could not remove cache directory", "path", opts.ociCache, "error", err)
+					}
+				}
+			}()
+		}
 
 		ociController, err := oci.NewController(opts.artifactsOutput, opts.ociCache)
 		if err != nil {
 			return fmt.Errorf("failed to create OCI controller with artifactsOutput: '%s' and ociCache: '%s': %v", opts.artifactsOutput, opts.ociCache, err)
 		}
+		ociController.Progress = reporter
+		ociController.Logger = slog.Default()
 
-		// If repo is specified, call helper function to download from a single repository
+		if opts.maxTotalSize != "" {
+			if ociController.MaxTotalSize, err = parseSize(opts.maxTotalSize); err != nil {
+				return fmt.Errorf("invalid size for --max-total-size: %v", err)
+			}
+		}
+
+		// Handle time-based downloads
+		if opts.since != "" {
+			if ociController.Since, err = timeutil.ParseDuration(opts.since); err != nil {
+				return fmt.Errorf("invalid time format for --since: %v", err)
+			}
+			slog.Info("Downloading latest artifacts", "since", ociController.Since)
+		}
+
+		var subject registry.Reference
+		if opts.referrersOf != "" {
+			if subject, err = oci.ParseSubject(opts.referrersOf); err != nil {
+				return err
+			}
+			ociController.Registry = subject.Registry
+		}
+
+		var repo, tag string
 		if opts.repo != "" {
-			repo, tag, err := parseRepoAndTag(opts.repo)
-			if err != nil {
+			if repo, tag, err = resolveRepo(cmd.Context(), ociController, opts.repo); err != nil {
 				return err
 			}
+		}
 
+		// In dry-run mode only resolve the manifests and print what a real run would produce
+		if opts.dryRun {
+			return dryRun(cmd.OutOrStdout(), ociController, repo, tag, subject)
+		}
+
+		// If repo is specified, call helper function to download from a single repository
+		if opts.repo != "" {
 			// Call ProcessTag to get details of the tag (implement as needed)
 			if err := ociController.ProcessTag(repo, tag, time.Now().Format(time.RFC1123)); err != nil {
 				return fmt.Errorf("failed to fetch tag: %v", err)
 			}
 		}
 
-		// If repos is specified, simulate a download from multiple repositories
+		// If repos is specified, plan and download all repositories together so that the
+		// size budget and disk space check cover the whole run
 		if len(opts.repos) > 0 {
-			var allErrors []error
-			for _, repo := range opts.repos {
-				log.Println("Processing repository:", repo)
+			slog.Info("Processing repositories", "repos", opts.repos)
 
-				errors := ociController.ProcessRepositories([]string{repo})
-				allErrors = append(allErrors, errors...)
+			errors := ociController.ProcessRepositories(opts.repos)
+			for _, err := range errors {
+				slog.Error("Error encountered during processing", "error", oci.ClassifyAuthError(err))
 			}
-
-			if len(allErrors) > 0 {
-				log.Println("Errors encountered during processing:")
-				for _, err := range allErrors {
-					log.Printf(" - %v\n", err)
-				}
+			if len(errors) > 0 {
+				return fmt.Errorf("failed to download %d tags or repositories", len(errors))
 			}
 		}
 
-		// Handle time-based downloads
-		if opts.since != "" {
-			duration, err := parseDuration(opts.since)
-			if err != nil {
-				return fmt.Errorf("invalid time format for --since: %v", err)
+		// If referrers-of is specified, download every artifact attached to the image
+		if opts.referrersOf != "" {
+			errors := ociController.ProcessReferrers(subject.Repository, subject.Reference, opts.artifactType)
+			for _, err := range errors {
+				slog.Error("Error encountered during processing", "error", oci.ClassifyAuthError(err))
+			}
+			if len(errors) > 0 {
+				return fmt.Errorf("failed to download %d referrers of %s", len(errors), opts.referrersOf)
 			}
-			fmt.Printf("Downloading latest artifacts from the last %s\n", duration)
 		}
 
 		return nil // Return nil if all operations succeeded
 	},
 }
 
+// dryRun plans the requested tags without downloading any layer and prints the plan.
+// It fails when a real run would abort on the size budget or disk space check, or when some
+// repositories or referrers could not be planned.
+func dryRun(w io.Writer, ociController *oci.Controller, repo, tag string, subject registry.Reference) error {
+	plan := &oci.Plan{}
+
+	if opts.repo != "" {
+		tagPlan, err := ociController.PlanTag(repo, tag, time.Now().Format(time.RFC1123))
+		if err != nil {
+			return fmt.Errorf("failed to plan tag: %v", err)
+		}
+		plan.Add(*tagPlan)
+	}
+
+	// Planning errors are reported after the plan of the repositories that could be resolved
+	var errors []error
+	if len(opts.repos) > 0 {
+		plan, errors = ociController.PlanRepositories(opts.repos)
+	}
+	if opts.referrersOf != "" {
+		plan, errors = ociController.PlanReferrers(subject.Repository, subject.Reference, opts.artifactType)
+	}
+	for _, err := range errors {
+		slog.Error("Error encountered during planning", "error", oci.ClassifyAuthError(err))
+	}
+
+	if err := printPlan(w, plan, opts.output); err != nil {
+		return err
+	}
+	if len(errors) > 0 {
+		if opts.referrersOf != "" {
+			return fmt.Errorf("failed to plan %d referrers of %s", len(errors), opts.referrersOf)
+		}
+		return fmt.Errorf("failed to plan %d repositories", len(errors))
+	}
+
+	return ociController.CheckPlan(plan)
+}
+
+// resolveRepo extracts the repository and tag from the given repo flag. OCI image layouts and
+// archives are opened as the source of the controller and named after their directory or file.
+func resolveRepo(ctx context.Context, ociController *oci.Controller, repoFlag string) (string, string, error) {
+	ref, isLocal, err := oci.ParseLocalReference(repoFlag)
+	if err != nil {
+		return "", "", err
+	}
+	if !isLocal {
+		return parseRepoAndTag(repoFlag)
+	}
+	if ref.Tag == "" {
+		return "", "", fmt.Errorf("tag is missing in the repo flag")
+	}
+
+	src, err := oci.OpenLocalSource(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+	ociController.Source = src
+	return ref.Name(), ref.Tag, nil
+}
+
 // parseRepoAndTag extracts the repository and tag from the given repo flag.
 func parseRepoAndTag(repoFlag string) (string, string, error) {
 	// Ensure the repoFlag starts with 'quay.io/'
@@ -173,33 +316,50 @@ func parseRepoAndTag(repoFlag string) (string, string, error) {
 	return parts[0], parts[1], nil
 }
 
-// parseDuration handles the custom duration format
-func parseDuration(since string) (time.Duration, error) {
-	if len(since) > 1 && since[len(since)-1] == 'd' {
-		days := since[:len(since)-1]
-		hours, err := time.ParseDuration(days + "h")
-		if err != nil {
-			return 0, err
+// parseSize parses a byte size with an optional decimal (KB, MB, GB, TB) or binary (KiB, MiB, GiB, TiB) unit.
+// A unit made of a single letter (K, M, G, T) is interpreted as binary.
+func parseSize(size string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+
+	trimmed := strings.TrimSpace(size)
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(strings.ToUpper(trimmed), strings.ToUpper(unit.suffix)) && (unit.suffix != "B" || len(trimmed) > 1) {
+			trimmed = strings.TrimSpace(trimmed[:len(trimmed)-len(unit.suffix)])
+			multiplier = unit.multiplier
+			break
 		}
-		return hours * 24, nil
 	}
 
-	// Parse the duration normally for other time units
-	duration, err := time.ParseDuration(since)
-	if err != nil {
-		return 0, err
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
 	}
-	return duration, nil
+	return int64(value * float64(multiplier)), nil
 }
 
 // Init initializes the download command and its flags
 func Init() *cobra.Command {
-	downloadCmd.Flags().StringVar(&opts.repo, "repo", "", "OCI repository and tag to download (e.g., quay.io/test/test:1.0)")
+	downloadCmd.Flags().StringVar(&opts.repo, "repo", "", "OCI repository and tag to download (e.g., quay.io/test/test:1.0, oci-layout:/path/to/dir:tag or oci-archive:/path/file.tar:tag)")
 	downloadCmd.Flags().StringSliceVar(&opts.repos, "repos", nil, "Set of OCI repositories to download from")
 	downloadCmd.Flags().StringVar(&opts.since, "since", "", "Time range to download the latest artifacts (e.g., 4h, 10m, 2d)")
 	downloadCmd.Flags().StringVar(&opts.ociCache, "oci-cache", "", "Directory where OCI artifacts will be cached (default: $HOME/.config/konflux-oci-artifacts/cache)")
 	downloadCmd.Flags().StringVar(&opts.artifactsOutput, "artifacts-output", "", "Mandatory path to store downloaded artifacts")
 	downloadCmd.Flags().BoolVar(&opts.noCache, "no-cache", true, "If true, removes the OCI cache after downloading artifacts")
+	downloadCmd.Flags().StringVar(&opts.maxTotalSize, "max-total-size", "", "Abort before downloading when the compressed size of all artifacts exceeds this budget (e.g., 500MB, 10GiB)")
+	downloadCmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Resolve tags and manifests only and print what would be downloaded")
+	downloadCmd.Flags().StringVarP(&opts.output, "output", "o", outputTable, "Output format of the dry run: table or json")
+	downloadCmd.Flags().StringVar(&opts.referrersOf, "referrers-of", "", "Download every artifact attached to this image (e.g., quay.io/org/image@sha256:...)")
+	downloadCmd.Flags().StringVar(&opts.artifactType, "artifact-type", "", "Only download the referrers of this artifact type (use with --referrers-of)")
+	downloadCmd.Flags().StringVar(&opts.progress, "progress", progress.ModeAuto, "Progress output: auto, tty, json (newline-delimited events on stderr) or none")
 
 	// Custom Help function for the download command
 	downloadCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
@@ -210,12 +370,19 @@ Usage:
   konflux-oci-artifacts download [flags]
 
 Available Flags:
-  --repo             Single OCI repository to download from (e.g., quay.io/test/test:1.0)
+  --repo             Single OCI repository to download from (e.g., quay.io/test/test:1.0),
+                     or a local OCI image layout or archive (oci-layout:/path/to/dir:tag, oci-archive:/path/file.tar:tag)
   --repos            Multiple OCI repositories to download from (use with --since)
+  --referrers-of     Download every artifact attached to an image (e.g., quay.io/org/image@sha256:...)
+  --artifact-type    Only download the referrers of this artifact type (use with --referrers-of)
   --since            Time range to download the latest artifacts (e.g., 4h, 10m, 2d)
   --oci-cache        Directory where OCI artifacts will be cached (default: $HOME/.config/konflux-oci-artifacts/cache)
   --artifacts-output Mandatory path to store downloaded artifacts
   --no-cache         If true, removes the OCI cache after downloading artifacts
+  --max-total-size   Abort before downloading when the compressed size of all artifacts exceeds this budget (e.g., 500MB, 10GiB)
+  --dry-run          Resolve tags and manifests only and print what would be downloaded
+  -o, --output       Output format of the dry run: table or json (default: table)
+  --progress         Progress output: auto, tty, json (newline-delimited events on stderr) or none (default: auto)
 
 Examples:
   Download from a single repository:
@@ -223,6 +390,18 @@ Examples:
 
   Download from multiple repositories within the last 2 days:
     konflux-oci-artifacts download --repos quay.io/repo1 quay.io/repo2 --since 2d --artifacts-output /path/to/output
+
+  Download an artifact handed over as an OCI archive:
+    konflux-oci-artifacts download --repo oci-archive:/media/usb/e2e.tar:v1 --artifacts-output /path/to/output
+
+  Download the test reports attached to an image:
+    konflux-oci-artifacts download --referrers-of quay.io/org/image@sha256:... --artifact-type application/vnd.konflux.e2e --artifacts-output /path/to/output
+
+  Check which tags, layers and output paths a download would produce:
+    konflux-oci-artifacts download --repos quay.io/repo1 --since 2d --artifacts-output /path/to/output --dry-run --output json
+
+  Emit machine readable progress events for a CI wrapper:
+    konflux-oci-artifacts download --repo quay.io/test/test:1.0 --artifacts-output /path/to/output --progress=json
 	`)
 	})
 
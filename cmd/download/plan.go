@@ -0,0 +1,60 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+)
+
+// Supported formats for the --output flag of a dry run.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printPlan writes the plan of a dry run to w in the requested format.
+func printPlan(w io.Writer, plan *oci.Plan, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	case outputTable:
+		return printPlanTable(w, plan)
+	default:
+		return fmt.Errorf("unsupported output format %q (expected %s or %s)", format, outputTable, outputJSON)
+	}
+}
+
+// printPlanTable writes one row per tag followed by one row per layer, and a summary line.
+func printPlanTable(w io.Writer, plan *oci.Plan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tTAG\tDIGEST\tSIZE\tOUTPUT")
+
+	for _, tag := range plan.Tags {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", tag.Repo, tag.Tag, tag.Digest, progress.FormatBytes(tag.CompressedSize), tag.OutputDir)
+		for _, layer := range tag.Layers {
+			details := []string{layer.MediaType}
+			if layer.Title != "" {
+				details = append(details, layer.Title)
+			}
+			if layer.Cached {
+				details = append(details, "cached")
+			}
+			fmt.Fprintf(tw, "\t\t%s\t%s\t%s\n", layer.Digest, progress.FormatBytes(layer.Size), strings.Join(details, ", "))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d tags, %s compressed, %s to download, ~%s extracted\n", len(plan.Tags),
+		progress.FormatBytes(plan.CompressedSize), progress.FormatBytes(plan.DownloadSize), progress.FormatBytes(plan.ExtractedSize))
+	return err
+}
@@ -0,0 +1,188 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+)
+
+// Supported formats for the --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// inspectOptions holds the configuration for the inspect command
+type inspectOptions struct {
+	// raw prints the manifest exactly as served by the repository.
+	raw bool
+
+	// output selects the output format: text or json.
+	output string
+}
+
+var opts = &inspectOptions{}
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [flags] <ref>",
+	Short: "Show the manifest, layers and annotations of an artifact",
+	Long: `Show the manifest, config, layers, annotations and referrers of an artifact without downloading its layers.
+
+The reference is a tag or digest of a registry repository, or a tag of an OCI image layout or archive.
+
+Examples:
+  - Inspect an artifact pushed to Quay:
+      konflux-oci-artifacts inspect quay.io/org/repo:tag
+
+  - Print the manifest exactly as served by the registry:
+      konflux-oci-artifacts inspect quay.io/org/repo@sha256:... --raw
+
+  - Inspect an artifact of an OCI image layout, as JSON:
+      konflux-oci-artifacts inspect oci-layout:/tmp/layout:tag --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if opts.output != outputText && opts.output != outputJSON {
+			return fmt.Errorf("unsupported output format %q (expected %s or %s)", opts.output, outputText, outputJSON)
+		}
+		if opts.raw && cmd.Flags().Changed("output") {
+			return fmt.Errorf("--raw cannot be combined with --output")
+		}
+
+		// The controller only reads the manifest, so its store lives in a throwaway directory
+		storeDir, err := os.MkdirTemp("", "konflux-oci-inspect-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(storeDir)
+
+		ociController, err := oci.NewController(storeDir, storeDir)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		repo, reference, err := ociController.OpenReference(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		inspection, err := ociController.Inspect(repo, reference)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		switch {
+		case opts.raw:
+			_, err = w.Write(append(bytes.TrimRight(inspection.RawManifest, "\n"), '\n'))
+			return err
+		case opts.output == outputJSON:
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(inspection)
+		default:
+			return printInspection(w, args[0], inspection)
+		}
+	},
+}
+
+// Init initializes the inspect command and its flags
+func Init() *cobra.Command {
+	inspectCmd.Flags().BoolVar(&opts.raw, "raw", false, "Print the manifest exactly as served by the repository")
+	inspectCmd.Flags().StringVarP(&opts.output, "output", "o", outputText, "Output format: text or json")
+
+	return inspectCmd
+}
+
+// printInspection writes a human-readable description of the artifact to w.
+func printInspection(w io.Writer, reference string, inspection *oci.Inspection) error {
+	manifest := inspection.Manifest
+	size := inspection.Descriptor.Size + manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Reference:\t%s\n", reference)
+	fmt.Fprintf(tw, "Digest:\t%s\n", inspection.Descriptor.Digest)
+	fmt.Fprintf(tw, "Media type:\t%s\n", inspection.Descriptor.MediaType)
+	fmt.Fprintf(tw, "Artifact type:\t%s\n", valueOrDash(manifest.ArtifactType))
+	fmt.Fprintf(tw, "Total size:\t%s\n", progress.FormatBytes(size))
+	if manifest.Subject != nil {
+		fmt.Fprintf(tw, "Subject:\t%s\n", manifest.Subject.Digest)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nConfig:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  Media type:\t%s\n", manifest.Config.MediaType)
+	fmt.Fprintf(tw, "  Digest:\t%s\n", manifest.Config.Digest)
+	fmt.Fprintf(tw, "  Size:\t%s\n", progress.FormatBytes(manifest.Config.Size))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(inspection.Config) > 0 && string(inspection.Config) != "{}" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, inspection.Config, "  ", "  "); err == nil {
+			fmt.Fprintf(w, "  %s\n", indented.String())
+		}
+	}
+
+	fmt.Fprintf(w, "\nLayers (%d):\n", len(manifest.Layers))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  DIGEST\tMEDIA TYPE\tSIZE\tTITLE")
+	for _, layer := range manifest.Layers {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", layer.Digest, layer.MediaType, progress.FormatBytes(layer.Size),
+			valueOrDash(layer.Annotations[ocispec.AnnotationTitle]))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nAnnotations (%d):\n", len(inspection.Annotations))
+	keys := make([]string, 0, len(inspection.Annotations))
+	for key := range inspection.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "  %s:\t%s\n", key, inspection.Annotations[key])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(inspection.Referrers) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\nReferrers (%d):\n", len(inspection.Referrers))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  DIGEST\tARTIFACT TYPE\tCREATED")
+	for _, referrer := range inspection.Referrers {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", referrer.Digest, valueOrDash(referrer.ArtifactType),
+			valueOrDash(referrer.Annotations[ocispec.AnnotationCreated]))
+	}
+	return tw.Flush()
+}
+
+// valueOrDash returns the value, or "-" when it is empty.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,266 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/flacatus/oras-puller/pkg/timeutil"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats for the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// Supported keys for the --sort flag.
+const (
+	sortDate = "date"
+	sortName = "name"
+	sortSize = "size"
+)
+
+// listOptions holds the configuration for the list command
+type listOptions struct {
+	// since restricts the listed tags to those modified within this time range (e.g., 4h, 2d).
+	since string
+
+	// match restricts the listed tags to those whose name matches this regular expression.
+	match string
+
+	// sort is the key the tags are sorted by: date (newest first), name or size (largest first).
+	sort string
+
+	// reverse inverts the sort order.
+	reverse bool
+
+	// limit caps the number of listed tags after sorting. Zero lists every tag.
+	limit int
+
+	// annotations are the manifest annotations shown for every tag.
+	annotations []string
+
+	// output selects the output format: table, json or yaml.
+	output string
+}
+
+var opts = &listOptions{}
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list [flags] <repo>",
+	Short: "List the artifact tags of a repository",
+	Long: `List the tags of a Quay repository with the digest, artifact type, size and annotations of their manifest.
+
+Only the manifests are fetched, no layer is downloaded. Use --since and --match to narrow down
+repositories with many tags before their manifests are resolved.
+
+Examples:
+  - List the artifacts pushed within the last day, newest first:
+      konflux-oci-artifacts list quay.io/org/repo --since 1d
+
+  - List the artifacts of pull request runs with their revision, as JSON:
+      konflux-oci-artifacts list quay.io/org/repo --match '^pr-' --annotation org.opencontainers.image.revision --output json
+
+  - List the ten largest artifacts:
+      konflux-oci-artifacts list quay.io/org/repo --sort size --limit 10`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch opts.output {
+		case outputTable, outputJSON, outputYAML:
+		default:
+			return fmt.Errorf("unsupported output format %q (expected %s, %s or %s)", opts.output, outputTable, outputJSON, outputYAML)
+		}
+		switch opts.sort {
+		case sortDate, sortName, sortSize:
+		default:
+			return fmt.Errorf("unsupported sort key %q (expected %s, %s or %s)", opts.sort, sortDate, sortName, sortSize)
+		}
+
+		var match *regexp.Regexp
+		if opts.match != "" {
+			var err error
+			if match, err = regexp.Compile(opts.match); err != nil {
+				return fmt.Errorf("invalid regular expression for --match: %v", err)
+			}
+		}
+
+		// The controller only reads manifests, so its store lives in a throwaway directory
+		storeDir, err := os.MkdirTemp("", "konflux-oci-list-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(storeDir)
+
+		ociController, err := oci.NewController(storeDir, storeDir)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		if opts.since != "" {
+			if ociController.Since, err = timeutil.ParseDuration(opts.since); err != nil {
+				return fmt.Errorf("invalid time format for --since: %v", err)
+			}
+		}
+
+		repo := strings.TrimPrefix(strings.TrimPrefix(args[0], "oci://"), "quay.io/")
+		tags, err := ociController.ListTags(repo)
+		if err != nil {
+			return err
+		}
+		tags = oci.MatchTags(tags, match)
+
+		// Sorting by name or date does not need the manifests, so the limit is applied before resolving them
+		if opts.sort != sortSize {
+			sortTags(tags, opts.sort, opts.reverse)
+			tags = limit(tags, opts.limit)
+		}
+
+		summaries, errors := ociController.DescribeTags(repo, tags)
+		for _, err := range errors {
+			slog.Error("Failed to describe tag", "error", oci.ClassifyAuthError(err))
+		}
+		if opts.sort == sortSize {
+			sortSummaries(summaries, opts.reverse)
+			summaries = limit(summaries, opts.limit)
+		}
+
+		for i := range summaries {
+			summaries[i].Annotations = selectAnnotations(summaries[i].Annotations, opts.annotations)
+		}
+
+		if err := printSummaries(cmd.OutOrStdout(), summaries, opts.annotations, opts.output); err != nil {
+			return err
+		}
+		if len(errors) > 0 {
+			return fmt.Errorf("failed to describe %d of %d tags", len(errors), len(tags))
+		}
+		return nil
+	},
+}
+
+// Init initializes the list command and its flags
+func Init() *cobra.Command {
+	listCmd.Flags().StringVar(&opts.since, "since", "", "Only list the tags modified within this time range (e.g., 4h, 10m, 2d)")
+	listCmd.Flags().StringVar(&opts.match, "match", "", "Only list the tags whose name matches this regular expression")
+	listCmd.Flags().StringVar(&opts.sort, "sort", sortDate, "Sort the tags by date (newest first), name or size (largest first)")
+	listCmd.Flags().BoolVar(&opts.reverse, "reverse", false, "Reverse the sort order")
+	listCmd.Flags().IntVar(&opts.limit, "limit", 0, "Maximum number of tags to list after sorting (0 lists every tag)")
+	listCmd.Flags().StringArrayVarP(&opts.annotations, "annotation", "a", nil, "Manifest annotation to show for every tag (repeatable)")
+	listCmd.Flags().StringVarP(&opts.output, "output", "o", outputTable, "Output format: table, json or yaml")
+
+	return listCmd
+}
+
+// sortTags sorts the tags by name, or by last modified date with the newest first.
+func sortTags(tags []oci.TagInfo, key string, reverse bool) {
+	dates := make(map[string]time.Time, len(tags))
+	for _, tag := range tags {
+		dates[tag.Name], _ = oci.ParseTagDate(tag.LastModified)
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		var less bool
+		if key == sortName {
+			less = tags[i].Name < tags[j].Name
+		} else {
+			less = dates[tags[i].Name].After(dates[tags[j].Name])
+		}
+		return less != reverse
+	})
+}
+
+// sortSummaries sorts the summaries by size with the largest first.
+func sortSummaries(summaries []oci.TagSummary, reverse bool) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return (summaries[i].Size > summaries[j].Size) != reverse
+	})
+}
+
+// limit returns the first n items, or every item when n is zero.
+func limit[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// selectAnnotations keeps the requested annotation keys.
+func selectAnnotations(annotations map[string]string, keys []string) map[string]string {
+	selected := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := annotations[key]; ok {
+			selected[key] = value
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// printSummaries writes the tag summaries to w in the requested format.
+func printSummaries(w io.Writer, summaries []oci.TagSummary, annotations []string, format string) error {
+	if summaries == nil {
+		summaries = []oci.TagSummary{}
+	}
+
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	case outputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(summaries); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return printTable(w, summaries, annotations)
+	}
+}
+
+// printTable writes one row per tag, with one column per selected annotation.
+func printTable(w io.Writer, summaries []oci.TagSummary, annotations []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"TAG", "LAST MODIFIED", "DIGEST", "ARTIFACT TYPE", "SIZE"}
+	for _, key := range annotations {
+		header = append(header, strings.ToUpper(key))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, summary := range summaries {
+		modified := "-"
+		if !summary.LastModified.IsZero() {
+			modified = summary.LastModified.UTC().Format(time.DateTime)
+		}
+		row := []string{summary.Name, modified, summary.Digest, summary.ArtifactType, progress.FormatBytes(summary.Size)}
+		for _, key := range annotations {
+			value := summary.Annotations[key]
+			if value == "" {
+				value = "-"
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
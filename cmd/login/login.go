@@ -0,0 +1,64 @@
+package login
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// loginCmd represents the login command
+var loginCmd = &cobra.Command{
+	Use:   "login --username <user> --password-stdin <registry>",
+	Short: "Log in to a registry",
+	Long: `Log in to a registry and save the credentials for the other commands.
+
+The credentials are verified against the registry, then saved to the credential helper configured in
+the registry configuration file, the platform default helper (pass, secretservice, osxkeychain or
+wincred) when the file configures none, or the file itself when no helper is available. The
+configuration file defaults to the Docker one; use --registry-config to log in with another file,
+such as the auth.json of podman.
+
+The password, or token, is read from stdin so that it does not end up in the shell history.
+
+Examples:
+  - Log in to Quay with a robot account:
+      echo "$QUAY_TOKEN" | konflux-oci-artifacts login quay.io --username 'org+robot' --password-stdin
+
+  - Log in with the auth.json of podman:
+      echo "$QUAY_TOKEN" | konflux-oci-artifacts login quay.io --username 'org+robot' --password-stdin --registry-config "$XDG_RUNTIME_DIR/containers/auth.json"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		credentialOptions := oci.GetCredentialOptions()
+		if credentialOptions.Username == "" || credentialOptions.Password == "" {
+			return fmt.Errorf("the --username and --password-stdin flags are required, the password is read from stdin")
+		}
+
+		registry := registryHost(args[0])
+		cred := auth.Credential{Username: credentialOptions.Username, Password: credentialOptions.Password}
+		configPath, err := oci.Login(cmd.Context(), registry, cred)
+		if err != nil {
+			return fmt.Errorf("failed to log in to %s: %w", registry, err)
+		}
+
+		slog.Info("Login succeeded", "registry", registry, "username", cred.Username, "config", configPath)
+		return nil
+	},
+}
+
+// Init initializes the login command
+func Init() *cobra.Command {
+	return loginCmd
+}
+
+// registryHost returns the host of a registry given as a URL or a repository reference
+// (e.g., https://quay.io/ or quay.io/org/repo).
+func registryHost(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry = strings.TrimPrefix(registry, "oci://")
+	host, _, _ := strings.Cut(registry, "/")
+	return host
+}
@@ -0,0 +1,37 @@
+package login
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/spf13/cobra"
+)
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout <registry>",
+	Short: "Log out from a registry",
+	Long: `Remove the credentials of a registry saved by the login command, from the credential helper or the
+registry configuration file selected with --registry-config (default: the Docker configuration).
+
+Examples:
+  - Log out from Quay:
+      konflux-oci-artifacts logout quay.io`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registry := registryHost(args[0])
+		configPath, err := oci.Logout(cmd.Context(), registry)
+		if err != nil {
+			return fmt.Errorf("failed to log out from %s: %w", registry, err)
+		}
+
+		slog.Info("Logout succeeded", "registry", registry, "config", configPath)
+		return nil
+	},
+}
+
+// InitLogout initializes the logout command
+func InitLogout() *cobra.Command {
+	return logoutCmd
+}
@@ -0,0 +1,197 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2"
+	ocistore "oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry"
+)
+
+// copyOptions holds the configuration for the copy command
+type copyOptions struct {
+	// referrers also copies the artifacts attached to the copied artifact.
+	referrers bool
+}
+
+var copyOpts = &copyOptions{}
+
+// endpoint is the source or destination of a copy: a registry repository, or an OCI image
+// layout or archive.
+type endpoint struct {
+	// local is the parsed reference of a layout or archive, when isLocal is true.
+	local   oci.LocalReference
+	isLocal bool
+
+	// remote is the parsed reference of a registry repository, when isLocal is false.
+	remote registry.Reference
+}
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy [flags] <src> <dst>",
+	Short: "Copy an artifact between repositories and layouts",
+	Long: `Copy an artifact, with every blob it references, from a repository or layout to another one.
+
+Sources and destinations are registry references (quay.io/org/repo:tag or quay.io/org/repo@sha256:...),
+or OCI image layouts and archives (oci-layout:/path/to/dir:tag, oci-archive:/path/file.tar:tag).
+The destination tag defaults to the source tag. Digests are preserved, blobs that already exist in
+the destination are not uploaded again, and within the same registry blobs are mounted from the
+source repository instead of being uploaded. An archive destination is replaced.
+
+Examples:
+  - Promote an artifact of the e2e repository to a long-term repository, with its attached reports:
+      konflux-oci-artifacts copy quay.io/org/e2e:pr-123 quay.io/org/archive:pr-123 --referrers
+
+  - Save an artifact to an archive for offline analysis:
+      konflux-oci-artifacts copy quay.io/org/e2e:pr-123 oci-archive:/tmp/pr-123.tar`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		src, err := parseEndpoint(args[0])
+		if err != nil {
+			return err
+		}
+		dst, err := parseEndpoint(args[1])
+		if err != nil {
+			return err
+		}
+		srcRef := src.reference()
+		if srcRef == "" {
+			return fmt.Errorf("source %q must include a tag or a digest", args[0])
+		}
+		dstRef := dst.reference()
+		if dstRef == "" {
+			dstRef = srcRef
+		}
+
+		srcTarget, err := openSource(ctx, src)
+		if err != nil {
+			return err
+		}
+
+		// An archive is written from a temporary layout once the copy is complete
+		tempDir, err := os.MkdirTemp("", "konflux-oci-copy-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		dstTarget, err := openDestination(dst, filepath.Join(tempDir, "layout"))
+		if err != nil {
+			return err
+		}
+
+		ociController, err := oci.NewController(tempDir, filepath.Join(tempDir, "store"))
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		copyOptions := oci.CopyOptions{Referrers: copyOpts.referrers}
+		if !src.isLocal && !dst.isLocal && src.remote.Registry == dst.remote.Registry && src.remote.Repository != dst.remote.Repository {
+			copyOptions.MountFrom = src.remote.Repository
+		}
+
+		stats := &oci.CopyStats{}
+		root, err := ociController.CopyArtifact(ctx, srcTarget, srcRef, dstTarget, dstRef, copyOptions, stats)
+		if err != nil {
+			return err
+		}
+
+		if dst.isLocal && dst.local.Scheme == oci.SchemeOCIArchive {
+			if err := oci.WriteArchive(filepath.Join(tempDir, "layout"), dst.local.Path); err != nil {
+				return err
+			}
+		}
+
+		logStats(stats)
+		fmt.Fprintf(cmd.OutOrStdout(), "Copied %s to %s\nDigest: %s\n", args[0], dst.name(dstRef), root.Digest)
+		return nil
+	},
+}
+
+// InitCopy initializes the copy command and its flags
+func InitCopy() *cobra.Command {
+	copyCmd.Flags().BoolVar(&copyOpts.referrers, "referrers", false, "Also copy the artifacts attached to the artifact, such as signatures and test reports")
+
+	return copyCmd
+}
+
+// parseEndpoint parses a registry reference, or the reference to an OCI image layout or archive.
+func parseEndpoint(reference string) (endpoint, error) {
+	local, isLocal, err := oci.ParseLocalReference(reference)
+	if err != nil {
+		return endpoint{}, err
+	}
+	if isLocal {
+		if strings.Contains(local.Tag, ",") {
+			return endpoint{}, fmt.Errorf("reference %q must include a single tag", reference)
+		}
+		return endpoint{local: local, isLocal: true}, nil
+	}
+
+	ref, err := registry.ParseReference(strings.TrimPrefix(reference, "oci://"))
+	if err != nil {
+		return endpoint{}, fmt.Errorf("invalid reference %q: %w", reference, err)
+	}
+	return endpoint{remote: ref}, nil
+}
+
+// reference returns the tag or digest of the endpoint, empty when it has none.
+func (e endpoint) reference() string {
+	if e.isLocal {
+		return e.local.Tag
+	}
+	return e.remote.Reference
+}
+
+// name returns the printable reference of the endpoint with the given tag or digest.
+func (e endpoint) name(reference string) string {
+	if e.isLocal {
+		return fmt.Sprintf("%s:%s:%s", e.local.Scheme, e.local.Path, reference)
+	}
+	ref := e.remote
+	ref.Reference = reference
+	return ref.String()
+}
+
+// openSource opens the endpoint for reading.
+func openSource(ctx context.Context, e endpoint) (oras.ReadOnlyTarget, error) {
+	if e.isLocal {
+		return oci.OpenLocalSource(ctx, e.local)
+	}
+	return oci.NewRemoteRepository(e.remote.Registry + "/" + e.remote.Repository)
+}
+
+// openDestination opens the endpoint for writing. An archive is staged in archiveLayoutDir.
+func openDestination(e endpoint, archiveLayoutDir string) (oras.Target, error) {
+	if !e.isLocal {
+		return oci.NewRemoteRepository(e.remote.Registry + "/" + e.remote.Repository)
+	}
+
+	layoutDir := e.local.Path
+	if e.local.Scheme == oci.SchemeOCIArchive {
+		layoutDir = archiveLayoutDir
+	}
+	store, err := ocistore.New(layoutDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout %s: %w", layoutDir, err)
+	}
+	return store, nil
+}
+
+// logStats logs the content handled by the copies.
+func logStats(stats *oci.CopyStats) {
+	slog.Info("Copied content", "copied", stats.Copied.Load(), "size", progress.FormatBytes(stats.Bytes.Load()),
+		"mounted", stats.Mounted.Load(), "skipped", stats.Skipped.Load())
+}
@@ -0,0 +1,160 @@
+package mirror
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/timeutil"
+	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2"
+	ocistore "oras.land/oras-go/v2/content/oci"
+)
+
+// mirrorOptions holds the configuration for the mirror command
+type mirrorOptions struct {
+	// repos are the Quay repositories to mirror.
+	repos []string
+
+	// to is the registry, optionally with a namespace, or the OCI image layout directory the
+	// repositories are mirrored to.
+	to string
+
+	// since restricts the mirrored tags to those modified within this time range (e.g., 4h, 2d).
+	since string
+
+	// match restricts the mirrored tags to those whose name matches this regular expression.
+	match string
+
+	// referrers also mirrors the artifacts attached to the mirrored artifacts.
+	referrers bool
+}
+
+var mirrorOpts = &mirrorOptions{}
+
+// mirrorCmd represents the mirror command
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror [flags] --repos <repo>... --to <registry>",
+	Short: "Mirror the tags of Quay repositories to another registry or a layout",
+	Long: `Mirror the tags of Quay repositories to another registry, keeping the repository names and tags.
+
+The destination is a registry host, optionally followed by a namespace (localhost:5000/mirror), or an
+OCI image layout directory (oci-layout:/path/to/dir) where each repository gets its own layout.
+Digests are preserved and blobs that already exist in the destination are not uploaded again, so
+running the same mirror again only copies the new tags.
+
+Examples:
+  - Mirror the artifacts of the last two days into a local registry:
+      konflux-oci-artifacts mirror --repos quay.io/org/repo1 quay.io/org/repo2 --to localhost:5000 --since 2d
+
+  - Mirror the pull request artifacts, with their referrers, into layouts for offline analysis:
+      konflux-oci-artifacts mirror --repos quay.io/org/repo --to oci-layout:/tmp/mirror --match '^pr-' --referrers`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(mirrorOpts.repos) == 0 {
+			return fmt.Errorf("the --repos flag is required")
+		}
+		if mirrorOpts.to == "" {
+			return fmt.Errorf("the --to flag is required")
+		}
+
+		var match *regexp.Regexp
+		if mirrorOpts.match != "" {
+			var err error
+			if match, err = regexp.Compile(mirrorOpts.match); err != nil {
+				return fmt.Errorf("invalid regular expression for --match: %v", err)
+			}
+		}
+
+		local, isLocal, err := oci.ParseLocalReference(mirrorOpts.to)
+		if err != nil {
+			return err
+		}
+		if isLocal && (local.Scheme != oci.SchemeOCILayout || local.Tag != "") {
+			return fmt.Errorf("--to must be a registry or an OCI image layout directory without tag")
+		}
+		to := strings.TrimSuffix(strings.TrimPrefix(mirrorOpts.to, "oci://"), "/")
+
+		storeDir, err := os.MkdirTemp("", "konflux-oci-mirror-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(storeDir)
+
+		ociController, err := oci.NewController(storeDir, storeDir)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		// The repositories keep their path under the destination, so without a namespace the source
+		// registry would be mirrored onto itself
+		if !isLocal && strings.EqualFold(to, ociController.Registry) {
+			return fmt.Errorf("--to %s is the registry of the mirrored repositories, add a namespace to mirror into", mirrorOpts.to)
+		}
+
+		if mirrorOpts.since != "" {
+			if ociController.Since, err = timeutil.ParseDuration(mirrorOpts.since); err != nil {
+				return fmt.Errorf("invalid time format for --since: %v", err)
+			}
+		}
+
+		stats := &oci.CopyStats{}
+		var errors []error
+		for _, repo := range mirrorOpts.repos {
+			repo = strings.TrimPrefix(strings.TrimPrefix(repo, "oci://"), "quay.io/")
+
+			tags, err := ociController.ListTags(repo)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			tags = oci.MatchTags(tags, match)
+			slog.Info("Mirroring repository", "repo", repo, "tags", len(tags))
+
+			var dst oras.Target
+			copyOptions := oci.CopyOptions{Referrers: mirrorOpts.referrers}
+			if isLocal {
+				layoutDir := filepath.Join(local.Path, filepath.FromSlash(repo))
+				if dst, err = ocistore.New(layoutDir); err != nil {
+					errors = append(errors, fmt.Errorf("failed to open OCI layout %s: %w", layoutDir, err))
+					continue
+				}
+			} else {
+				if dst, err = oci.NewRemoteRepository(to + "/" + repo); err != nil {
+					errors = append(errors, err)
+					continue
+				}
+				// Within the source registry the blobs are mounted instead of uploaded
+				if host, _, _ := strings.Cut(to, "/"); host == ociController.Registry {
+					copyOptions.MountFrom = repo
+				}
+			}
+
+			errors = append(errors, ociController.MirrorTags(cmd.Context(), repo, tags, dst, copyOptions, stats)...)
+		}
+
+		logStats(stats)
+		for _, err := range errors {
+			slog.Error("Error encountered during mirroring", "error", oci.ClassifyAuthError(err))
+		}
+		if len(errors) > 0 {
+			return fmt.Errorf("failed to mirror %d tags or repositories", len(errors))
+		}
+		return nil
+	},
+}
+
+// Init initializes the mirror command and its flags
+func Init() *cobra.Command {
+	mirrorCmd.Flags().StringSliceVar(&mirrorOpts.repos, "repos", nil, "Quay repositories to mirror (e.g., quay.io/org/repo1 quay.io/org/repo2)")
+	mirrorCmd.Flags().StringVar(&mirrorOpts.to, "to", "", "Registry, optionally with a namespace, or OCI image layout directory to mirror to (e.g., localhost:5000/mirror, oci-layout:/tmp/mirror)")
+	mirrorCmd.Flags().StringVar(&mirrorOpts.since, "since", "", "Only mirror the tags modified within this time range (e.g., 4h, 10m, 2d)")
+	mirrorCmd.Flags().StringVar(&mirrorOpts.match, "match", "", "Only mirror the tags whose name matches this regular expression")
+	mirrorCmd.Flags().BoolVar(&mirrorOpts.referrers, "referrers", false, "Also mirror the artifacts attached to the mirrored artifacts")
+
+	return mirrorCmd
+}
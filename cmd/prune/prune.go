@@ -0,0 +1,266 @@
+package prune
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/timeutil"
+	"github.com/spf13/cobra"
+)
+
+// quayTokenEnv is the environment variable holding the Quay OAuth token used to delete tags.
+const quayTokenEnv = "QUAY_TOKEN"
+
+// Supported formats for the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// pruneOptions holds the configuration for the prune command
+type pruneOptions struct {
+	// olderThan is the age after which tags are deleted (e.g., 4d, 12h).
+	olderThan string
+
+	// keepLast keeps the given number of most recently modified tags.
+	keepLast int
+
+	// keepMatch keeps the tags whose name matches this regular expression.
+	keepMatch string
+
+	// keepAnnotations keeps the tags whose manifest has one of these annotations, as key or key=value.
+	keepAnnotations []string
+
+	// mode selects what is deleted: the tags only, or their manifests.
+	mode string
+
+	// delete performs the deletions. Without it the command only reports what would be deleted.
+	delete bool
+
+	// yes skips the confirmation prompt before deleting.
+	yes bool
+
+	// output selects the format of the deletion report: table or json.
+	output string
+}
+
+var opts = &pruneOptions{}
+
+// report is the deletion report printed by the prune command.
+type report struct {
+	Repository string              `json:"repository"`
+	Mode       string              `json:"mode"`
+	DryRun     bool                `json:"dryRun"`
+	Decisions  []oci.PruneDecision `json:"decisions"`
+}
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune [flags] <repo>",
+	Short: "Delete the tags of a repository according to retention policies",
+	Long: fmt.Sprintf(`Delete the tags of a Quay repository that are older than a retention period.
+
+A tag is deleted when it is older than --older-than and none of the keep policies applies to it.
+Tags whose modification date or manifest cannot be read are always kept.
+
+By default the command only reports what would be deleted. Pass --delete to delete the tags, after
+a confirmation prompt that --yes skips. In tag mode the tags are deleted through the Quay API with
+the OAuth token of the %s environment variable; in manifest mode the manifests are deleted through
+the registry API, together with every tag that references them.
+
+Examples:
+  - Show the tags older than 4 days, keeping the 10 most recent ones:
+      konflux-oci-artifacts prune quay.io/org/repo --keep-last 10
+
+  - Delete the tags older than 2 weeks, except releases and pinned artifacts:
+      konflux-oci-artifacts prune quay.io/org/repo --older-than 14d --keep-match '^v[0-9]' \
+        --keep-annotation dev.konflux-ci.keep=true --delete --yes`, quayTokenEnv),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if opts.mode != oci.PruneTags && opts.mode != oci.PruneManifests {
+			return fmt.Errorf("unsupported mode %q (expected %s or %s)", opts.mode, oci.PruneTags, oci.PruneManifests)
+		}
+		if opts.output != outputTable && opts.output != outputJSON {
+			return fmt.Errorf("unsupported output format %q (expected %s or %s)", opts.output, outputTable, outputJSON)
+		}
+		if opts.keepLast < 0 {
+			return fmt.Errorf("--keep-last must not be negative")
+		}
+
+		policy := oci.RetentionPolicy{KeepLast: opts.keepLast, KeepAnnotations: opts.keepAnnotations}
+		var err error
+		if policy.OlderThan, err = timeutil.ParseDuration(opts.olderThan); err != nil || policy.OlderThan < 0 {
+			return fmt.Errorf("invalid time format for --older-than: %q", opts.olderThan)
+		}
+		if opts.keepMatch != "" {
+			if policy.KeepMatch, err = regexp.Compile(opts.keepMatch); err != nil {
+				return fmt.Errorf("invalid regular expression for --keep-match: %v", err)
+			}
+		}
+
+		quayToken := os.Getenv(quayTokenEnv)
+		if opts.delete && opts.mode == oci.PruneTags && quayToken == "" {
+			return fmt.Errorf("deleting tags requires a Quay OAuth token in the %s environment variable", quayTokenEnv)
+		}
+
+		// The controller only reads manifests, so its store lives in a throwaway directory
+		storeDir, err := os.MkdirTemp("", "konflux-oci-prune-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(storeDir)
+
+		ociController, err := oci.NewController(storeDir, storeDir)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		repo := strings.TrimPrefix(strings.TrimPrefix(args[0], "oci://"), "quay.io/")
+		tags, err := ociController.ListTags(repo)
+		if err != nil {
+			return err
+		}
+
+		result := report{
+			Repository: "quay.io/" + repo,
+			Mode:       opts.mode,
+			DryRun:     !opts.delete,
+			Decisions:  ociController.PlanPrune(repo, tags, policy, opts.mode),
+		}
+		selected := 0
+		for _, decision := range result.Decisions {
+			if decision.Delete {
+				selected++
+			}
+		}
+
+		failures := 0
+		if opts.delete && selected > 0 {
+			if !opts.yes {
+				if _, _, err := printDecisions(cmd.OutOrStdout(), result.Decisions, true); err != nil {
+					return err
+				}
+				confirmed, err := confirm(cmd.InOrStdin(), cmd.ErrOrStderr(),
+					fmt.Sprintf("Delete %d of %d tags from %s?", selected, len(result.Decisions), result.Repository))
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return fmt.Errorf("prune aborted, no tag was deleted")
+				}
+			}
+			failures = ociController.Prune(cmd.Context(), repo, result.Decisions, opts.mode, quayToken)
+		}
+
+		if err := printReport(cmd.OutOrStdout(), result, opts.output); err != nil {
+			return err
+		}
+		if failures > 0 {
+			return fmt.Errorf("failed to delete %d of %d tags", failures, selected)
+		}
+		return nil
+	},
+}
+
+// Init initializes the prune command and its flags
+func Init() *cobra.Command {
+	pruneCmd.Flags().StringVar(&opts.olderThan, "older-than", fmt.Sprintf("%.0fd", oci.DefaultRetention.Hours()/24), "Delete the tags modified before this time range (e.g., 12h, 14d); 0 selects every tag")
+	pruneCmd.Flags().IntVar(&opts.keepLast, "keep-last", 0, "Keep the given number of most recently modified tags")
+	pruneCmd.Flags().StringVar(&opts.keepMatch, "keep-match", "", "Keep the tags whose name matches this regular expression")
+	pruneCmd.Flags().StringArrayVar(&opts.keepAnnotations, "keep-annotation", nil, "Keep the tags whose manifest has this annotation, as key or key=value (repeatable)")
+	pruneCmd.Flags().StringVar(&opts.mode, "mode", oci.PruneTags, "Delete the tags only (tag), or their manifests and every tag referencing them (manifest)")
+	pruneCmd.Flags().BoolVar(&opts.delete, "delete", false, "Delete the selected tags instead of only reporting them")
+	pruneCmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "Do not ask for confirmation before deleting")
+	pruneCmd.Flags().StringVarP(&opts.output, "output", "o", outputTable, "Format of the deletion report: table or json")
+
+	return pruneCmd
+}
+
+// confirm asks a yes/no question and reads the answer. Without an interactive terminal the
+// question cannot be answered, and --yes is required instead.
+func confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	if file, ok := in.(*os.File); ok {
+		info, err := file.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false, fmt.Errorf("cannot ask for confirmation without a terminal, pass --yes to delete")
+		}
+	}
+
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// printReport writes the decision of every tag to w in the requested format.
+func printReport(w io.Writer, result report, format string) error {
+	if format == outputJSON {
+		if result.Decisions == nil {
+			result.Decisions = []oci.PruneDecision{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	selected, failed, err := printDecisions(w, result.Decisions, result.DryRun)
+	if err != nil {
+		return err
+	}
+
+	if result.DryRun {
+		_, err = fmt.Fprintf(w, "\n%d of %d tags would be deleted from %s (dry run, pass --delete to delete them)\n",
+			selected, len(result.Decisions), result.Repository)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\nDeleted %d of %d tags from %s in %s mode, %d failed\n",
+		selected-failed, len(result.Decisions), result.Repository, result.Mode, failed)
+	return err
+}
+
+// printDecisions writes one row per tag with its action and reason, and returns the number of
+// tags selected for deletion and of failed deletions.
+func printDecisions(w io.Writer, decisions []oci.PruneDecision, dryRun bool) (int, int, error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tLAST MODIFIED\tDIGEST\tACTION\tREASON")
+	selected, failed := 0, 0
+	for _, decision := range decisions {
+		modified, digest := "-", decision.Digest
+		if !decision.LastModified.IsZero() {
+			modified = decision.LastModified.UTC().Format(time.DateTime)
+		}
+		if digest == "" {
+			digest = "-"
+		}
+
+		action, reason := "keep", decision.Reason
+		switch {
+		case !decision.Delete:
+		case decision.Error != "":
+			action, reason = "failed", decision.Error
+			failed++
+		case dryRun:
+			action = "delete"
+		default:
+			action = "deleted"
+		}
+		if decision.Delete {
+			selected++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", decision.Tag, modified, digest, action, reason)
+	}
+	return selected, failed, tw.Flush()
+}
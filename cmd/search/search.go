@@ -0,0 +1,207 @@
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/timeutil"
+	"github.com/spf13/cobra"
+)
+
+// Supported formats for the --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// searchOptions holds the configuration for the search command
+type searchOptions struct {
+	// repos are the Quay repositories whose artifacts are searched.
+	repos []string
+
+	// since restricts the searched tags to those modified within this time range (e.g., 4h, 2d).
+	since string
+
+	// match restricts the searched tags to those whose name matches this regular expression.
+	match string
+
+	// ignoreCase makes the pattern case insensitive.
+	ignoreCase bool
+
+	// filesWithMatches prints the matching files only, once each, instead of the matching lines.
+	filesWithMatches bool
+
+	// ociCache is the directory of the OCI store the layers are downloaded to.
+	ociCache string
+
+	// output selects the output format: text or json (one match per line).
+	output string
+}
+
+var opts = &searchOptions{}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search [flags] <pattern> --repos <repo>... --since <duration>",
+	Short: "Search the files of recent artifacts for a regular expression",
+	Long: `Search the files of the artifacts of Quay repositories modified within a time range for a regular
+expression (RE2 syntax). The pattern is matched against the path of every file and against every
+line of text files; binary files are only matched by path.
+
+Layers are streamed entry by entry, nothing is extracted to disk. They are downloaded to the OCI
+cache first, so searching the same artifacts again does not download them again.
+
+Matches are printed as <repo>:<tag> <file>:<line>: <text>, or <repo>:<tag> <file> when the path matches.
+
+Examples:
+  - Find the runs of the last three days that panicked:
+      konflux-oci-artifacts search 'panic: runtime error' --repos quay.io/org/repo1 quay.io/org/repo2 --since 3d
+
+  - List the JUnit reports of pull request runs with a failure, as JSON:
+      konflux-oci-artifacts search '<failure' --repos quay.io/org/repo --since 1d --match '^pr-' -l --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(opts.repos) == 0 {
+			return fmt.Errorf("the --repos flag is required")
+		}
+		if opts.since == "" {
+			return fmt.Errorf("the --since flag is required")
+		}
+		if opts.output != outputText && opts.output != outputJSON {
+			return fmt.Errorf("unsupported output format %q (expected %s or %s)", opts.output, outputText, outputJSON)
+		}
+
+		expression := args[0]
+		if opts.ignoreCase {
+			expression = "(?i)" + expression
+		}
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %v", err)
+		}
+
+		var match *regexp.Regexp
+		if opts.match != "" {
+			if match, err = regexp.Compile(opts.match); err != nil {
+				return fmt.Errorf("invalid regular expression for --match: %v", err)
+			}
+		}
+
+		if opts.ociCache == "" {
+			if opts.ociCache, err = oci.DefaultCacheDir(); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(opts.ociCache, os.ModePerm); err != nil {
+			return fmt.Errorf("could not create cache directory: %v", err)
+		}
+
+		// Nothing is extracted, the output directory is only required by the controller
+		ociController, err := oci.NewController(os.TempDir(), opts.ociCache)
+		if err != nil {
+			return fmt.Errorf("failed to create OCI controller: %w", err)
+		}
+		ociController.Logger = slog.Default()
+
+		if ociController.Since, err = timeutil.ParseDuration(opts.since); err != nil {
+			return fmt.Errorf("invalid time format for --since: %v", err)
+		}
+
+		out := bufio.NewWriter(cmd.OutOrStdout())
+		defer out.Flush()
+		printer := newPrinter(out)
+
+		var errors []error
+		for _, repo := range opts.repos {
+			repo = strings.TrimPrefix(strings.TrimPrefix(repo, "oci://"), "quay.io/")
+
+			tags, err := ociController.ListTags(repo)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			tags = oci.MatchTags(tags, match)
+			slog.Info("Searching repository", "repo", repo, "tags", len(tags))
+
+			for _, tag := range tags {
+				if err := ociController.SearchTag(repo, tag.Name, pattern, printer.print); err != nil {
+					errors = append(errors, err)
+				}
+				// Matches are shown as soon as a tag has been searched
+				if err := out.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, err := range errors {
+			slog.Error("Error encountered during search", "error", oci.ClassifyAuthError(err))
+		}
+		if len(errors) > 0 {
+			return fmt.Errorf("failed to search %d tags or repositories", len(errors))
+		}
+		if printer.matches == 0 {
+			slog.Info("No matches found")
+		}
+		return nil
+	},
+}
+
+// Init initializes the search command and its flags
+func Init() *cobra.Command {
+	searchCmd.Flags().StringSliceVar(&opts.repos, "repos", nil, "Quay repositories to search (e.g., quay.io/org/repo1 quay.io/org/repo2)")
+	searchCmd.Flags().StringVar(&opts.since, "since", "", "Only search the tags modified within this time range (e.g., 4h, 10m, 2d)")
+	searchCmd.Flags().StringVar(&opts.match, "match", "", "Only search the tags whose name matches this regular expression")
+	searchCmd.Flags().BoolVarP(&opts.ignoreCase, "ignore-case", "i", false, "Match the pattern case insensitively")
+	searchCmd.Flags().BoolVarP(&opts.filesWithMatches, "files-with-matches", "l", false, "Print the matching files only, instead of the matching lines")
+	searchCmd.Flags().StringVar(&opts.ociCache, "oci-cache", "", "Path to the OCI cache directory (default: $HOME/.config/konflux-oci-artifacts/cache)")
+	searchCmd.Flags().StringVarP(&opts.output, "output", "o", outputText, "Output format: text or json (one match per line)")
+
+	return searchCmd
+}
+
+// printer writes the matches of the search in the selected output format.
+type printer struct {
+	w       io.Writer
+	encoder *json.Encoder
+	matches int
+
+	// printed holds the files already printed with --files-with-matches.
+	printed map[string]bool
+}
+
+// newPrinter returns a printer writing to w.
+func newPrinter(w io.Writer) *printer {
+	return &printer{w: w, encoder: json.NewEncoder(w), printed: map[string]bool{}}
+}
+
+// print writes a match, or its file the first time it matches with --files-with-matches.
+func (p *printer) print(match oci.SearchMatch) error {
+	p.matches++
+	if opts.filesWithMatches {
+		key := match.Repo + ":" + match.Tag + " " + match.File
+		if p.printed[key] {
+			return nil
+		}
+		p.printed[key] = true
+		match.Line, match.Text = 0, ""
+	}
+
+	if opts.output == outputJSON {
+		return p.encoder.Encode(match)
+	}
+	var err error
+	if match.Line == 0 {
+		_, err = fmt.Fprintf(p.w, "%s:%s %s\n", match.Repo, match.Tag, match.File)
+	} else {
+		_, err = fmt.Fprintf(p.w, "%s:%s %s:%d: %s\n", match.Repo, match.Tag, match.File, match.Line, match.Text)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/webui"
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout is the time given to the requests in flight to complete once the server is stopped.
+const shutdownTimeout = 5 * time.Second
+
+// serveOptions holds the configuration for the serve command
+type serveOptions struct {
+	// artifactsOutput is the output directory of the download command to serve.
+	artifactsOutput string
+
+	// addr is the host and port the server listens on.
+	addr string
+}
+
+var opts = &serveOptions{}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve --artifacts-output <dir> [--addr host:port]",
+	Short: "Browse the downloaded artifacts in a web browser",
+	Long: `Serve the artifacts output directory of the download command over HTTP.
+
+The index lists the repositories, dates and tags of the directory. Every tag shows the annotations of
+its manifest and its files, text files are shown with syntax highlighting and line numbers, logs can
+be filtered by text and severity, and every file can be downloaded. Only the files below the output
+directory are served.
+
+The server listens on localhost by default. Listen on all interfaces to share a triage machine with
+teammates, knowing that the artifacts are served without authentication.
+
+Examples:
+  - Browse the artifacts downloaded to ./artifacts on http://127.0.0.1:8080:
+      konflux-oci-artifacts serve --artifacts-output ./artifacts
+
+  - Share them with the network:
+      konflux-oci-artifacts serve --artifacts-output /srv/artifacts --addr 0.0.0.0:8080`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if opts.artifactsOutput == "" {
+			return fmt.Errorf("the --artifacts-output flag is mandatory")
+		}
+
+		server, err := webui.NewServer(opts.artifactsOutput)
+		if err != nil {
+			return err
+		}
+		server.Logger = slog.Default()
+
+		listener, err := net.Listen("tcp", opts.addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", opts.addr, err)
+		}
+
+		httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errs := make(chan error, 1)
+		go func() { errs <- httpServer.Serve(listener) }()
+		slog.Info("Serving artifacts", "path", opts.artifactsOutput, "url", "http://"+listener.Addr().String())
+
+		select {
+		case err := <-errs:
+			return fmt.Errorf("failed to serve artifacts: %w", err)
+		case <-ctx.Done():
+		}
+
+		slog.Info("Stopping server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to stop server: %w", err)
+		}
+		return nil
+	},
+}
+
+// Init initializes the serve command and its flags
+func Init() *cobra.Command {
+	serveCmd.Flags().StringVar(&opts.artifactsOutput, "artifacts-output", "", "Output directory of the download command to serve")
+	serveCmd.Flags().StringVar(&opts.addr, "addr", "127.0.0.1:8080", "Host and port to listen on")
+
+	return serveCmd
+}
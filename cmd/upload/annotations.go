@@ -0,0 +1,104 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// loadAnnotations reads the annotations of the annotation file and of the --annotation flags.
+// The annotation file uses the ORAS format: a JSON object keyed by file name, or by $manifest
+// and $config, whose values map annotation keys to values. Flags are given as key=value and
+// are added to the manifest annotations, overriding the values of the annotation file.
+// Every file name key must reference one of the uploaded files.
+func loadAnnotations(annotationFile string, flags []string, fileRefs []string) (map[string]map[string]string, error) {
+	annotations := make(map[string]map[string]string)
+
+	if annotationFile != "" {
+		content, err := os.ReadFile(annotationFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read annotation file: %w", err)
+		}
+		if err := json.Unmarshal(content, &annotations); err != nil {
+			return nil, fmt.Errorf("failed to parse annotation file %s: %w", annotationFile, err)
+		}
+	}
+
+	manifestAnnotations, err := parseAnnotationFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifestAnnotations) > 0 {
+		if annotations[AnnotationManifest] == nil {
+			annotations[AnnotationManifest] = make(map[string]string)
+		}
+		for k, v := range manifestAnnotations {
+			annotations[AnnotationManifest][k] = v
+		}
+	}
+
+	if err := validateAnnotations(annotations, fileRefs); err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+// parseAnnotationFlags parses the key=value pairs of the --annotation flags.
+func parseAnnotationFlags(flags []string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for _, flag := range flags {
+		key, value, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid annotation %q: expected key=value", flag)
+		}
+		if _, exists := annotations[key]; exists {
+			return nil, fmt.Errorf("duplicate annotation key %q", key)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// validateAnnotations checks the annotation keys and that every file name matches an uploaded file.
+func validateAnnotations(annotations map[string]map[string]string, fileRefs []string) error {
+	files := make(map[string]bool, len(fileRefs))
+	for _, fileRef := range fileRefs {
+		filename, _, err := Parse(fileRef, "")
+		if err != nil {
+			return err
+		}
+		files[filename] = true
+	}
+
+	for target, values := range annotations {
+		switch {
+		case target == AnnotationManifest || target == AnnotationConfig:
+		case strings.HasPrefix(target, "$"):
+			return fmt.Errorf("unknown annotation target %q (expected %s, %s or a file name)", target, AnnotationManifest, AnnotationConfig)
+		case !files[target]:
+			return fmt.Errorf("annotations are given for %q, which is not among the uploaded files", target)
+		}
+
+		for key := range values {
+			if err := validateAnnotationKey(key); err != nil {
+				return fmt.Errorf("invalid annotation for %s: %w", target, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAnnotationKey rejects empty keys and keys containing whitespace or control characters.
+func validateAnnotationKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("annotation key must not be empty")
+	}
+	for _, r := range key {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("annotation key %q must not contain whitespace or control characters", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// appendOptions holds the configuration for the append command
+type appendOptions struct {
+	// dest is the reference of the existing artifact tag (e.g., quay.io/org/repo:tag).
+	dest string
+
+	// onConflict is the policy applied to files whose title matches an existing layer: fail, replace or skip.
+	onConflict string
+
+	// include restricts the files packed from folders to those matching at least one glob.
+	include []string
+
+	// exclude skips the files and folders matching any glob when packing folders.
+	exclude []string
+
+	// annotationFile is the path of a JSON file with annotations keyed by file name, $manifest or $config.
+	annotationFile string
+
+	// annotations are key=value pairs merged into the manifest annotations.
+	annotations []string
+}
+
+// Initialize a global instance of appendOptions
+var appendOpts = &appendOptions{}
+
+// appendCmd represents the append command
+var appendCmd = &cobra.Command{
+	Use:   "append [flags] <file>[:type] [...]",
+	Short: "Append files and folders to an existing artifact tag",
+	Long: `Append files to an artifact already pushed to an OCI compliant repository.
+
+The existing manifest is resolved and its layers are reused without downloading them. The new files
+are pushed as additional layers, the annotations are merged and the tag is moved to the new manifest.
+The command fails if the tag is moved by someone else while the files are appended.
+
+Examples:
+  - Append the logs of a retried step to the artifact of a pipeline run:
+      konflux-oci-artifacts append --dest quay.io/org/repo:tag ./step-logs
+
+  - Replace a report that is already part of the artifact:
+      konflux-oci-artifacts append --dest quay.io/org/repo:tag --on-conflict replace junit.xml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch appendOpts.onConflict {
+		case oci.ConflictFail, oci.ConflictReplace, oci.ConflictSkip:
+		default:
+			return fmt.Errorf("invalid --on-conflict value %q (expected %s, %s or %s)", appendOpts.onConflict, oci.ConflictFail, oci.ConflictReplace, oci.ConflictSkip)
+		}
+
+		repository, tags, err := parseDestination(appendOpts.dest)
+		if err != nil {
+			return err
+		}
+		if len(tags) != 1 {
+			return fmt.Errorf("destination %q must reference exactly one existing tag", appendOpts.dest)
+		}
+		tag := tags[0]
+		logger := slog.With("repo", repository, "tag", tag)
+
+		annotations, err := loadAnnotations(appendOpts.annotationFile, appendOpts.annotations, args)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		repo, err := oci.NewRemoteRepository(repository)
+		if err != nil {
+			return err
+		}
+
+		baseDesc, baseBytes, err := oras.FetchBytes(ctx, repo, tag, oras.DefaultFetchBytesOptions)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s:%s: %w", repository, tag, err)
+		}
+		if baseDesc.MediaType != ocispec.MediaTypeImageManifest {
+			return fmt.Errorf("cannot append to %s:%s: unsupported manifest media type %s", repository, tag, baseDesc.MediaType)
+		}
+		var base ocispec.Manifest
+		if err := json.Unmarshal(baseBytes, &base); err != nil {
+			return fmt.Errorf("failed to parse manifest of %s:%s: %w", repository, tag, err)
+		}
+		logger = logger.With("base", baseDesc.Digest.String())
+
+		store, err := file.New("")
+		if err != nil {
+			return fmt.Errorf("failed to create file store: %w", err)
+		}
+		defer store.Close()
+		memoryStore := memory.New()
+
+		tempDir, err := os.MkdirTemp("", "konflux-oci-append-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		packOpts := dirPackOptions{
+			tempDir: tempDir,
+			filter:  oci.PathFilter{Include: appendOpts.include, Exclude: appendOpts.exclude},
+		}
+		descs, err := loadFiles(ctx, store, annotations, args, packOpts)
+		if err != nil {
+			return fmt.Errorf("failed to load files: %w", err)
+		}
+
+		layers, err := oci.MergeLayers(base.Layers, descs, appendOpts.onConflict, logger)
+		if err != nil {
+			return err
+		}
+
+		configDesc := base.Config
+		if len(annotations[AnnotationConfig]) > 0 {
+			configDesc.Annotations = mergeAnnotations(configDesc.Annotations, annotations[AnnotationConfig])
+		}
+
+		// The creation date is refreshed by PackManifest, since the appended artifact is a new manifest.
+		manifestAnnotations := mergeAnnotations(base.Annotations, annotations[AnnotationManifest])
+		delete(manifestAnnotations, ocispec.AnnotationCreated)
+
+		artifactType := base.ArtifactType
+		if artifactType == "" && base.Config.MediaType == ocispec.MediaTypeEmptyJSON {
+			artifactType = defaultArtifactType
+		}
+
+		manifestOpts := oras.PackManifestOptions{
+			Subject:             base.Subject,
+			Layers:              layers,
+			ConfigDescriptor:    &configDesc,
+			ManifestAnnotations: manifestAnnotations,
+		}
+		root, err := oras.PackManifest(ctx, memoryStore, oras.PackManifestVersion1_1, artifactType, manifestOpts)
+		if err != nil {
+			return fmt.Errorf("failed to pack manifest: %w", err)
+		}
+		logger = logger.With("digest", root.Digest.String())
+
+		// Layers and config of the existing artifact are already in the repository, so CopyGraph
+		// skips them and only the appended layers and the new manifest are pushed.
+		union := MultiReadOnlyTarget(memoryStore, store)
+		if err := oras.CopyGraph(ctx, union, repo, root, oras.DefaultCopyGraphOptions); err != nil {
+			return fmt.Errorf("failed to push artifact to %s: %w", repository, err)
+		}
+
+		if err := oci.EnsureTagUnchanged(ctx, repo, tag, baseDesc); err != nil {
+			return err
+		}
+		if err := repo.Tag(ctx, root, tag); err != nil {
+			return fmt.Errorf("failed to tag artifact in %s: %w", repository, err)
+		}
+
+		logger.Info("Successfully appended artifacts", "layers", len(layers), "appended", len(layers)-len(base.Layers))
+		fmt.Fprintf(cmd.OutOrStdout(), "Pushed %s\nDigest: %s\n", appendOpts.dest, root.Digest)
+
+		return nil
+	},
+}
+
+// InitAppend initializes the append command and its flags
+func InitAppend() *cobra.Command {
+	appendCmd.Flags().StringVarP(&appendOpts.dest, "dest", "D", "", "Reference of the existing artifact tag (e.g., quay.io/org/repo:tag)")
+	appendCmd.Flags().StringVar(&appendOpts.onConflict, "on-conflict", oci.ConflictFail, "Policy for files named like an existing layer: fail, replace or skip")
+
+	appendCmd.Flags().StringArrayVar(&appendOpts.include, "include", nil, "Only pack the folder files matching this glob (repeatable, e.g., '*.log')")
+	appendCmd.Flags().StringArrayVar(&appendOpts.exclude, "exclude", nil, "Skip the folder files and subfolders matching this glob (repeatable), in addition to "+oci.IgnoreFileName)
+
+	appendCmd.Flags().StringVar(&appendOpts.annotationFile, "annotation-file", "", "JSON file with annotations keyed by file name, "+AnnotationManifest+" or "+AnnotationConfig)
+	appendCmd.Flags().StringArrayVarP(&appendOpts.annotations, "annotation", "a", nil, "Manifest annotation as key=value (repeatable), merged into the existing annotations")
+
+	appendCmd.MarkFlagRequired("dest")
+
+	return appendCmd
+}
+
+// mergeAnnotations returns a copy of base with the values of overrides applied on top.
+func mergeAnnotations(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
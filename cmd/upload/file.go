@@ -5,13 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2/content/file"
 )
 
+// dirPackOptions controls how directories are packed into layers.
+type dirPackOptions struct {
+	// tempDir holds the packed archives until they are pushed.
+	tempDir string
+
+	// filter selects the entries of each directory that are packed.
+	filter oci.PathFilter
+}
+
 // Parse parses file reference on unix.
 func Parse(reference string, defaultMetadata string) (filePath, metadata string, err error) {
 	i := strings.LastIndex(reference, ":")
@@ -26,7 +37,9 @@ func Parse(reference string, defaultMetadata string) (filePath, metadata string,
 	return filePath, metadata, nil
 }
 
-func loadFiles(ctx context.Context, store *file.Store, annotations map[string]map[string]string, fileRefs []string) ([]ocispec.Descriptor, error) {
+// loadFiles adds the referenced files and directories to the store and returns their layer descriptors.
+// Directories are packed into deterministic tar+gzip archives according to packOpts.
+func loadFiles(ctx context.Context, store *file.Store, annotations map[string]map[string]string, fileRefs []string, packOpts dirPackOptions) ([]ocispec.Descriptor, error) {
 	var files []ocispec.Descriptor
 	for _, fileRef := range fileRefs {
 		filename, mediaType, err := Parse(fileRef, "")
@@ -40,7 +53,12 @@ func loadFiles(ctx context.Context, store *file.Store, annotations map[string]ma
 			name = filepath.ToSlash(name)
 		}
 
-		file, err := addFile(ctx, store, name, mediaType, filename)
+		var file ocispec.Descriptor
+		if info, statErr := os.Stat(filename); statErr == nil && info.IsDir() {
+			file, err = addDirectory(ctx, store, name, mediaType, filename, packOpts)
+		} else {
+			file, err = addFile(ctx, store, name, mediaType, filename)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -69,3 +87,36 @@ func addFile(ctx context.Context, store *file.Store, name string, mediaType stri
 	}
 	return file, nil
 }
+
+// addDirectory packs the directory into a tar+gzip archive and adds it to the store under the given name.
+// The layer is annotated so that ORAS compatible clients, and the download command, unpack it on pull.
+func addDirectory(ctx context.Context, store *file.Store, name string, mediaType string, dir string, packOpts dirPackOptions) (ocispec.Descriptor, error) {
+	if mediaType == "" {
+		mediaType = ocispec.MediaTypeImageLayerGzip
+	}
+
+	archive, err := os.CreateTemp(packOpts.tempDir, "*.tar.gz")
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to create archive for %s: %w", dir, err)
+	}
+	defer archive.Close()
+
+	tarDigest, err := oci.PackDirectory(dir, archive, packOpts.filter)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := archive.Close(); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to write archive for %s: %w", dir, err)
+	}
+
+	desc, err := addFile(ctx, store, name, mediaType, archive.Name())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if desc.Annotations == nil {
+		desc.Annotations = make(map[string]string)
+	}
+	desc.Annotations[oci.AnnotationUnpack] = "true"
+	desc.Annotations[oci.AnnotationDigest] = tarDigest.String()
+	return desc, nil
+}
@@ -0,0 +1,141 @@
+package upload
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/provenance"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry"
+)
+
+// maxTagLength is the maximum length of a tag accepted by the distribution specification.
+const maxTagLength = 128
+
+// timestampLayout formats the {timestamp} placeholder so that it is valid in a tag and sorts chronologically.
+const timestampLayout = "20060102-150405"
+
+// placeholderPattern matches the placeholders of a tag template, such as {pr} or {sha:7}.
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)(?::([^{}]+))?\}`)
+
+// invalidTagChars matches the characters that are not allowed in a tag.
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// tagValues holds the values the placeholders of tag templates are resolved from.
+type tagValues struct {
+	// annotations are the manifest annotations, including the detected provenance.
+	annotations map[string]string
+
+	// getenv looks up environment variables for the {env:VAR} placeholder.
+	getenv func(string) string
+
+	// now is the time used by the {timestamp} and {date} placeholders, shared by all templates.
+	now time.Time
+}
+
+// resolveTags resolves the tag templates and appends them to the literal tags, dropping duplicates.
+// Supported placeholders:
+//
+//	{pipelinerun}       the pipeline run annotation
+//	{pr}                the pull request annotation
+//	{sha} or {sha:N}    the revision annotation, optionally shortened to N characters
+//	{timestamp}         the upload time in UTC, as 20060102-150405
+//	{date}              the upload date in UTC, as 2006-01-02
+//	{env:VAR}           the value of the environment variable VAR
+//	{annotation:KEY}    the value of the manifest annotation KEY
+//
+// Characters not allowed in tags are replaced with "-" and an error is returned when a value is missing.
+func resolveTags(tags, templates []string, values tagValues) ([]string, error) {
+	seen := make(map[string]bool)
+	var resolved []string
+	for _, tag := range tags {
+		if err := (registry.Reference{Reference: tag}).ValidateReferenceAsTag(); err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			resolved = append(resolved, tag)
+		}
+	}
+
+	for _, template := range templates {
+		tag, err := resolveTagTemplate(template, values)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := (registry.Reference{Reference: tag}).ValidateReferenceAsTag(); err != nil {
+			return nil, fmt.Errorf("tag template %q resolved to the invalid tag %q: %w", template, tag, err)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			resolved = append(resolved, tag)
+		}
+	}
+
+	return resolved, nil
+}
+
+// resolveTagTemplate replaces the placeholders of a single template.
+func resolveTagTemplate(template string, values tagValues) (string, error) {
+	var resolveErr error
+	tag := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		value, err := placeholderValue(match[1], match[2], values)
+		if err != nil && resolveErr == nil {
+			resolveErr = fmt.Errorf("tag template %q: %w", template, err)
+		}
+		return invalidTagChars.ReplaceAllString(value, "-")
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	if strings.ContainsAny(tag, "{}") {
+		return "", fmt.Errorf("tag template %q has an unknown or malformed placeholder", template)
+	}
+
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+	return tag, nil
+}
+
+// placeholderValue returns the value of a placeholder and its optional argument.
+func placeholderValue(name, arg string, values tagValues) (string, error) {
+	var value, source string
+	switch name {
+	case "pipelinerun":
+		value, source = values.annotations[provenance.AnnotationPipelineRun], "annotation "+provenance.AnnotationPipelineRun
+	case "pr":
+		value, source = values.annotations[provenance.AnnotationPullRequest], "annotation "+provenance.AnnotationPullRequest
+	case "sha":
+		value, source = values.annotations[ocispec.AnnotationRevision], "annotation "+ocispec.AnnotationRevision
+		if value != "" && arg != "" {
+			length, err := strconv.Atoi(arg)
+			if err != nil || length <= 0 {
+				return "", fmt.Errorf("invalid length %q in {sha:%s}", arg, arg)
+			}
+			if length < len(value) {
+				value = value[:length]
+			}
+		}
+	case "timestamp":
+		return values.now.UTC().Format(timestampLayout), nil
+	case "date":
+		return values.now.UTC().Format("2006-01-02"), nil
+	case "env":
+		value, source = values.getenv(arg), "environment variable "+arg
+	case "annotation":
+		value, source = values.annotations[arg], "annotation "+arg
+	default:
+		return "", fmt.Errorf("unknown placeholder {%s}", name)
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("%s is not set", source)
+	}
+	return value, nil
+}
@@ -5,22 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/provenance"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/file"
 	"oras.land/oras-go/v2/content/memory"
+	ocistore "oras.land/oras-go/v2/content/oci"
 	"oras.land/oras-go/v2/errdef"
-	"oras.land/oras-go/v2/registry/remote"
-	"oras.land/oras-go/v2/registry/remote/auth"
-	"oras.land/oras-go/v2/registry/remote/credentials"
-	"oras.land/oras-go/v2/registry/remote/retry"
+	"oras.land/oras-go/v2/registry"
 )
 
 // Pre-defined annotation keys for annotation file
@@ -29,10 +30,55 @@ const (
 	AnnotationConfig   = "$config"
 )
 
+// quayTokenEnv is the environment variable holding the Quay OAuth token used to set tag expirations.
+const quayTokenEnv = "QUAY_TOKEN"
+
+// defaultArtifactType is the artifact type recorded in the manifest when --artifact-type is not set.
+const defaultArtifactType = "application/vnd.unknown.artifact.v1"
+
 // uploadOptions holds the configuration for the upload command
 type uploadOptions struct {
-	dest         string
+	// dest is the destination reference of the artifact (e.g., quay.io/org/repo:tag).
+	// Several tags can be given separated by commas (e.g., quay.io/org/repo:tag1,tag2).
+	dest string
+
+	// artifactType is the artifact type recorded in the pushed manifest.
 	artifactType string
+
+	// include restricts the files packed from folders to those matching at least one glob.
+	include []string
+
+	// exclude skips the files and folders matching any glob when packing folders,
+	// in addition to the patterns of the .ociignore file of each folder.
+	exclude []string
+
+	// annotationFile is the path of a JSON file with annotations keyed by file name, $manifest or $config.
+	annotationFile string
+
+	// annotations are key=value pairs added to the manifest annotations.
+	annotations []string
+
+	// noProvenance disables the annotations detected from the CI environment.
+	noProvenance bool
+
+	// expiresAfter is the period after which Quay expires the pushed tags (e.g., 7d).
+	expiresAfter string
+
+	// setTagExpiration also sets the expiration of the pushed tags through the Quay API.
+	setTagExpiration bool
+
+	// tags are additional tags or tag templates, such as pr-{pr}-{sha:7}, resolved from the annotations.
+	tags []string
+
+	// subject is the image the artifact is attached to (e.g., quay.io/org/image@sha256:...).
+	// The artifact is pushed into the repository of the subject.
+	subject string
+
+	// verify re-resolves the pushed tags and checks every blob of the manifest after the push.
+	verify bool
+
+	// verifyRoundtrip also downloads every layer again and compares its checksum with the local file.
+	verifyRoundtrip bool
 }
 
 // Initialize a global instance of uploadOptions
@@ -40,107 +86,266 @@ var opts = &uploadOptions{}
 
 // uploadCmd represents the upload command
 var uploadCmd = &cobra.Command{
-	Use:   "upload",
+	Use:   "upload [flags] <file>[:type] [...]",
 	Short: "Upload files and folders to OCI storage",
 	Long: `Upload files to an OCI (Open Container Initiative) compliant repository.
 
+When running in Tekton, Konflux or GitHub Actions, the source repository, revision, pull request and
+pipeline run are detected from the environment and recorded as manifest annotations. Annotations given
+with --annotation or --annotation-file override the detected values; --no-provenance disables detection.
+
 Examples:
   - Upload multiple files:
-      konflux-oci-artifacts upload --dest oci://myrepo file1.tar file2.tar
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag file1.tar file2.tar
 
   - Upload multiple folders:
-      konflux-oci-artifacts upload --dest oci://myrepo ./folder1 ./folder2
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag ./folder1 ./folder2
+
+  - Upload both files and folders with a custom artifact type:
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag --artifact-type application/vnd.konflux.e2e file1.tar ./folder1
+
+  - Upload a folder without its temporary files (see also the .ociignore file of the folder):
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag --exclude '*.tmp' --exclude 'cache/' ./folder1
 
-  - Upload both files and folders:
-      konflux-oci-artifacts upload --dest oci://myrepo file1.tar ./folder1`,
+  - Upload with manifest annotations and per-file annotations from an annotation file:
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag --annotation org.opencontainers.image.revision=abc123 \
+        --annotation-file annotations.json junit.xml ./logs
+
+  - Upload an artifact that Quay deletes after a week, also setting the expiration through the Quay API:
+      QUAY_TOKEN=... konflux-oci-artifacts upload --dest quay.io/org/repo:tag --expires-after 7d --set-tag-expiration ./folder
+
+  - Write the artifact to an OCI image layout directory or archive instead of a registry:
+      konflux-oci-artifacts upload --dest oci-layout:/path/to/layout:tag ./folder
+      konflux-oci-artifacts upload --dest oci-archive:/path/to/e2e.tar:tag ./folder
+
+  - Upload and tag the artifact several times:
+      konflux-oci-artifacts upload --dest quay.io/org/repo:tag1,tag2 junit.xml:application/xml
+
+  - Attach test reports to the image they tested, so they can be found through its referrers:
+      konflux-oci-artifacts upload --subject quay.io/org/image@sha256:... --artifact-type application/vnd.konflux.e2e ./reports
+
+  - Upload and tag the artifact by pull request, by commit and by pipeline run:
+      konflux-oci-artifacts upload --dest quay.io/org/repo --tag 'pr-{pr}-{sha:7}' --tag '{sha}' --tag '{pipelinerun}-{timestamp}' ./folder
+
+    Tag templates support {pipelinerun}, {pr}, {sha}, {sha:N}, {timestamp}, {date}, {env:VAR} and
+    {annotation:KEY}, resolved from the manifest annotations, including the detected provenance.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		pula := args[1:]
-		fmt.Println("###")
-		fmt.Println(pula)
-		fmt.Println("####a")
-		fmt.Println(opts.dest)
-		// Ensure the 'dest' flag is provided
-		if opts.dest == "" {
-			return fmt.Errorf("destination must be specified using --dest flag")
+		// Ensure the 'dest' flag is provided, unless the artifact goes to the repository of its subject
+		if opts.dest == "" && opts.subject == "" {
+			return fmt.Errorf("destination must be specified using --dest or --subject flag")
 		}
 
-		store, err := file.New("")
-		if err != nil {
-			return err
+		var subject *registry.Reference
+		if opts.subject != "" {
+			ref, err := oci.ParseSubject(opts.subject)
+			if err != nil {
+				return err
+			}
+			subject = &ref
 		}
-		defer store.Close()
-		memoryStore := memory.New()
-		ociController, err := oci.NewController("./test", "./test-cache")
 
-		repoz, tagz, err := parseRepoAndTag("quay.io/konflux-test-storage/konflux-team/e2e-tests:konflux-e2e-fdv6k")
-		if err != nil {
-			return err
+		var (
+			repository string
+			tags       []string
+			localDest  oci.LocalReference
+			isLocal    bool
+			err        error
+		)
+		if opts.dest != "" {
+			if localDest, isLocal, err = oci.ParseLocalReference(opts.dest); err != nil {
+				return err
+			}
 		}
-
-		// Call ProcessTag to get details of the tag (implement as needed)
-		if err := ociController.ProcessTag(repoz, tagz, time.Now().Format(time.RFC1123)); err != nil {
-			return fmt.Errorf("failed to fetch tag: %v", err)
+		switch {
+		case isLocal:
+			if subject != nil || opts.setTagExpiration {
+				return fmt.Errorf("--subject and --set-tag-expiration require a registry destination")
+			}
+			repository = localDest.Scheme + ":" + localDest.Path
+			if localDest.Tag != "" {
+				tags = strings.Split(localDest.Tag, ",")
+			}
+		case opts.dest != "":
+			if repository, tags, err = parseDestination(opts.dest); err != nil {
+				return err
+			}
+		}
+		if subject != nil {
+			subjectRepository := subject.Registry + "/" + subject.Repository
+			if repository != "" && repository != subjectRepository {
+				return fmt.Errorf("destination %s must be the repository of the subject %s, since referrers are stored next to their subject", repository, subjectRepository)
+			}
+			repository = subjectRepository
 		}
+		logger := slog.With("repo", repository)
 
-		// Find the deepest directory containing files
-		deepestDir, _ := findDirWithFiles("./test")
-		fmt.Println(deepestDir)
-		pula = append(pula, deepestDir)
+		if opts.setTagExpiration && opts.expiresAfter == "" {
+			return fmt.Errorf("--set-tag-expiration requires --expires-after")
+		}
+		var expiresAfter time.Duration
+		if opts.expiresAfter != "" {
+			if expiresAfter, err = oci.ParseExpiresAfter(opts.expiresAfter); err != nil {
+				return err
+			}
+		}
+		quayToken := os.Getenv(quayTokenEnv)
+		if opts.setTagExpiration && quayToken == "" {
+			return fmt.Errorf("--set-tag-expiration requires a Quay OAuth token in the %s environment variable", quayTokenEnv)
+		}
 
-		if deepestDir != "" {
-			fmt.Printf("Deepest directory with files: %s\n", deepestDir)
-		} else {
-			fmt.Println("No directories with files found.")
+		artifactType := opts.artifactType
+		if artifactType == "" {
+			artifactType = defaultArtifactType
 		}
 
-		ann, _ := ociController.FetchOCIContainerAnnotations(repoz, tagz)
+		annotations, err := loadAnnotations(opts.annotationFile, opts.annotations, args)
+		if err != nil {
+			return err
+		}
+		if !opts.noProvenance {
+			// Annotations given by the user take precedence over the detected ones.
+			detected := provenance.Detect(os.Getenv)
+			if len(detected) > 0 {
+				logger.Debug("Detected provenance annotations", "provider", detected[provenance.AnnotationProvider])
+				annotations[AnnotationManifest] = mergeAnnotations(detected, annotations[AnnotationManifest])
+			}
+		}
 
-		fmt.Println(ann.Annotations)
+		tags, err = resolveTags(tags, opts.tags, tagValues{
+			annotations: annotations[AnnotationManifest],
+			getenv:      os.Getenv,
+			now:         time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		// Artifacts attached to a subject may be pushed by digest only, with no tag to expire
+		if opts.setTagExpiration && len(tags) == 0 {
+			return fmt.Errorf("--set-tag-expiration requires a tag in --dest or --tag")
+		}
+		logger = logger.With("tag", strings.Join(tags, ","))
 
-		packOpts := oras.PackManifestOptions{
-			ManifestAnnotations: ann.Annotations,
+		ctx := cmd.Context()
+		store, err := file.New("")
+		if err != nil {
+			return fmt.Errorf("failed to create file store: %w", err)
 		}
+		defer store.Close()
+		memoryStore := memory.New()
 
-		descs, err := loadFiles(context.Background(), store, make(map[string]map[string]string), pula)
+		tempDir, err := os.MkdirTemp("", "konflux-oci-upload-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
 
+		packOpts := dirPackOptions{
+			tempDir: tempDir,
+			filter:  oci.PathFilter{Include: opts.include, Exclude: opts.exclude},
+		}
+		descs, err := loadFiles(ctx, store, annotations, args, packOpts)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to load files: %w", err)
+		}
+
+		manifestOpts := oras.PackManifestOptions{
+			Layers:              descs,
+			ManifestAnnotations: annotations[AnnotationManifest],
+			ConfigAnnotations:   annotations[AnnotationConfig],
+		}
+		if opts.expiresAfter != "" {
+			configDesc, err := pushExpirationConfig(ctx, memoryStore, opts.expiresAfter, annotations[AnnotationConfig])
+			if err != nil {
+				return err
+			}
+			manifestOpts.ConfigDescriptor = &configDesc
+		}
+		var target oras.Target
+		if isLocal {
+			layoutDir := localDest.Path
+			if localDest.Scheme == oci.SchemeOCIArchive {
+				// The archive is written from a temporary layout once the artifact is complete.
+				layoutDir = filepath.Join(tempDir, "layout")
+			}
+			if target, err = ocistore.New(layoutDir); err != nil {
+				return fmt.Errorf("failed to open OCI layout %s: %w", layoutDir, err)
+			}
+		} else {
+			if target, err = oci.NewRemoteRepository(repository); err != nil {
+				return err
+			}
 		}
 
-		packOpts.Layers = descs
+		if subject != nil {
+			subjectDesc, err := target.Resolve(ctx, subject.Reference)
+			if err != nil {
+				return fmt.Errorf("failed to resolve subject %s: %w", opts.subject, err)
+			}
+			// The registry falls back to the referrers tag schema when it lacks the Referrers API.
+			manifestOpts.Subject = &ocispec.Descriptor{
+				MediaType: subjectDesc.MediaType,
+				Digest:    subjectDesc.Digest,
+				Size:      subjectDesc.Size,
+			}
+			logger = logger.With("subject", subjectDesc.Digest.String())
+		}
 
-		root, err := oras.PackManifest(context.Background(), memoryStore, 2, "application/vnd.unknown.artifact.v1", packOpts)
+		root, err := oras.PackManifest(ctx, memoryStore, oras.PackManifestVersion1_1, artifactType, manifestOpts)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to pack manifest: %w", err)
 		}
-		if err = memoryStore.Tag(context.Background(), root, root.Digest.String()); err != nil {
+		if err = memoryStore.Tag(ctx, root, root.Digest.String()); err != nil {
 			return err
 		}
+		logger = logger.With("digest", root.Digest.String())
 
+		// Push the manifest and its layers to the first tag, or by digest when no tag is given
+		dstRef := root.Digest.String()
+		if len(tags) > 0 {
+			dstRef = tags[0]
+		}
 		union := MultiReadOnlyTarget(memoryStore, store)
-		fmt.Println(union)
-		// Simulate upload logic
-		fmt.Printf("Successfully uploaded artifacts to %s with artifact type %s", opts.dest, opts.artifactType)
+		if _, err := oras.Copy(ctx, union, root.Digest.String(), target, dstRef, oras.DefaultCopyOptions); err != nil {
+			return fmt.Errorf("failed to push artifact to %s: %w", repository, err)
+		}
+		if len(tags) > 1 {
+			if _, err := oras.TagN(ctx, target, root.Digest.String(), tags[1:], oras.DefaultTagNOptions); err != nil {
+				return fmt.Errorf("failed to tag artifact in %s: %w", repository, err)
+			}
+		}
 
-		reg := "quay.io"
-		repo, err := remote.NewRepository(reg + "/konflux-test-storage/konflux-team/e2e-tests")
-		if err != nil {
-			panic(err)
+		if opts.verify || opts.verifyRoundtrip {
+			if err := verifyPush(ctx, target, store, root, tags, opts.verifyRoundtrip, logger); err != nil {
+				return err
+			}
 		}
 
-		credStore, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
-		if err != nil {
-			fmt.Errorf("failed to create credential store: %w", err)
+		if isLocal && localDest.Scheme == oci.SchemeOCIArchive {
+			if err := oci.WriteArchive(filepath.Join(tempDir, "layout"), localDest.Path); err != nil {
+				return err
+			}
 		}
-		repo.Client = &auth.Client{
-			Client:     retry.DefaultClient,
-			Cache:      auth.NewCache(),
-			Credential: credentials.Credential(credStore),
+
+		if opts.setTagExpiration {
+			host, repoPath, _ := strings.Cut(repository, "/")
+			expiration := time.Now().Add(expiresAfter)
+			for _, tag := range tags {
+				if err := oci.SetQuayTagExpiration(ctx, host, repoPath, tag, expiration, quayToken); err != nil {
+					return err
+				}
+			}
+			logger.Info("Set tag expiration", "tags", len(tags), "expiration", expiration.UTC().Format(time.RFC3339))
 		}
-		_, err = oras.Copy(context.Background(), union, root.Digest.String(), repo, "konflux-e2e-fdv6k", oras.DefaultCopyOptions)
-		if err != nil {
-			fmt.Println(err)
+
+		logger.Info("Successfully uploaded artifacts", "artifactType", artifactType, "layers", len(descs))
+		for _, tag := range tags {
+			fmt.Fprintf(cmd.OutOrStdout(), "Pushed %s:%s\n", repository, tag)
+		}
+		if len(tags) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Pushed %s@%s\n", repository, root.Digest)
 		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Digest: %s\n", root.Digest)
 
 		return nil
 	},
@@ -149,15 +354,75 @@ Examples:
 // Init initializes the upload command and its flags
 func Init() *cobra.Command {
 	// Bind flags to the global opts instance
-	uploadCmd.Flags().StringVarP(&opts.dest, "dest", "D", "", "Destination repository (e.g., oci://myrepo)")
-	uploadCmd.Flags().StringVarP(&opts.artifactType, "artifact-type", "T", "", "Set the artifact type for the upload")
+	uploadCmd.Flags().StringVarP(&opts.dest, "dest", "D", "", "Destination reference, several tags can be separated by commas (e.g., quay.io/org/repo:tag1,tag2, oci-layout:/path/to/dir:tag or oci-archive:/path/file.tar:tag)")
+	uploadCmd.Flags().StringArrayVarP(&opts.tags, "tag", "t", nil, "Additional tag or tag template (repeatable), e.g., 'pr-{pr}-{sha:7}' or '{pipelinerun}-{timestamp}'")
+	uploadCmd.Flags().StringVarP(&opts.artifactType, "artifact-type", "T", "", "Set the artifact type for the upload (default: "+defaultArtifactType+")")
 
-	// Mark destination as a required flag
-	uploadCmd.MarkFlagRequired("dest")
+	uploadCmd.Flags().StringArrayVar(&opts.include, "include", nil, "Only pack the folder files matching this glob (repeatable, e.g., '*.log')")
+	uploadCmd.Flags().StringArrayVar(&opts.exclude, "exclude", nil, "Skip the folder files and subfolders matching this glob (repeatable), in addition to "+oci.IgnoreFileName)
+
+	uploadCmd.Flags().StringVar(&opts.annotationFile, "annotation-file", "", "JSON file with annotations keyed by file name, "+AnnotationManifest+" or "+AnnotationConfig)
+	uploadCmd.Flags().StringArrayVarP(&opts.annotations, "annotation", "a", nil, "Manifest annotation as key=value (repeatable), overrides the annotation file")
+	uploadCmd.Flags().BoolVar(&opts.noProvenance, "no-provenance", false, "Do not add the source, revision and pipeline run annotations detected from the CI environment")
+
+	uploadCmd.Flags().StringVar(&opts.expiresAfter, "expires-after", "", "Expire the pushed tags after this period through the "+oci.LabelExpiresAfter+" label (e.g., 12h, 7d, 2w)")
+	uploadCmd.Flags().BoolVar(&opts.setTagExpiration, "set-tag-expiration", false, "Also set the tag expiration through the Quay API, using the token of "+quayTokenEnv)
+
+	uploadCmd.Flags().BoolVar(&opts.verify, "verify", true, "Check that the pushed tags resolve to the uploaded manifest and that every blob exists")
+	uploadCmd.Flags().BoolVar(&opts.verifyRoundtrip, "verify-roundtrip", false, "Also download every layer again and compare its checksum with the local file")
+	uploadCmd.Flags().StringVar(&opts.subject, "subject", "", "Attach the artifact to this image (e.g., quay.io/org/image@sha256:...), pushing it into the image repository")
 
 	return uploadCmd
 }
 
+// parseDestination splits the destination into the repository reference and its tags.
+// The destination may be prefixed with oci:// and may list several tags separated by commas.
+func parseDestination(dest string) (string, []string, error) {
+	dest = strings.TrimPrefix(dest, "oci://")
+
+	var tags []string
+	if i := strings.LastIndex(dest, ":"); i > strings.LastIndex(dest, "/") {
+		tags = strings.Split(dest[i+1:], ",")
+		dest = dest[:i]
+	}
+
+	ref, err := registry.ParseReference(dest)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid destination %q: %w", dest, err)
+	}
+	if ref.Reference != "" {
+		return "", nil, fmt.Errorf("destination %q must reference a tag, not a digest", dest)
+	}
+
+	for _, tag := range tags {
+		tagRef := ref
+		tagRef.Reference = tag
+		if err := tagRef.ValidateReferenceAsTag(); err != nil {
+			return "", nil, fmt.Errorf("invalid tag %q in destination: %w", tag, err)
+		}
+	}
+
+	return ref.String(), tags, nil
+}
+
+// pushExpirationConfig pushes an image config carrying the Quay expiration label, so that it is used
+// instead of the empty config, and returns its descriptor with the config annotations applied.
+func pushExpirationConfig(ctx context.Context, pusher content.Pusher, expiresAfter string, annotations map[string]string) (ocispec.Descriptor, error) {
+	config, err := oci.ExpirationConfig(expiresAfter)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	configDesc, err := oras.PushBytes(ctx, pusher, ocispec.MediaTypeImageConfig, config)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to push config: %w", err)
+	}
+	// PackManifest ignores the config annotations when the config descriptor is given.
+	if len(annotations) > 0 {
+		configDesc.Annotations = annotations
+	}
+	return configDesc, nil
+}
+
 type multiReadOnlyTarget struct {
 	targets []oras.ReadOnlyTarget
 }
@@ -209,61 +474,3 @@ func (m *multiReadOnlyTarget) Fetch(ctx context.Context, target ocispec.Descript
 	}
 	return nil, lastErr
 }
-
-// parseRepoAndTag extracts the repository and tag from the given repo flag.
-func parseRepoAndTag(repoFlag string) (string, string, error) {
-	// Ensure the repoFlag starts with 'quay.io/'
-	if !strings.HasPrefix(repoFlag, "quay.io/") {
-		return "", "", fmt.Errorf("the repository must start with 'quay.io/'")
-	}
-
-	// Remove 'quay.io/' prefix and split the repo and tag using the ':' character
-	repoFlag = strings.TrimPrefix(repoFlag, "quay.io/")
-	parts := strings.SplitN(repoFlag, ":", 2)
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("tag is missing in the repo flag")
-	}
-
-	return parts[0], parts[1], nil
-}
-
-// Function to check if a directory contains files
-func containsFiles(path string) bool {
-	files, err := os.ReadDir(path)
-	if err != nil {
-		return false
-	}
-	for _, file := range files {
-		if !file.IsDir() {
-			// Return true if any file is found in the directory
-			return true
-		}
-	}
-	// Return false if no files are found
-	return false
-}
-
-// Function to recursively find the first directory with files and stop further traversal
-func findDirWithFiles(root string) (string, error) {
-	var dirWithFiles string
-
-	// Walk function to process each directory and file
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		// Only check directories
-		if info.IsDir() {
-			// Check if the directory contains files
-			if containsFiles(path) {
-				// If it contains files, set this as the directory and stop further recursion
-				dirWithFiles = path
-				return filepath.SkipDir
-			}
-		}
-		return nil
-	})
-
-	// Return the directory and any errors encountered
-	return dirWithFiles, err
-}
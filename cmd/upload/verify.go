@@ -0,0 +1,118 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// verifyPush checks that the target serves the artifact that was packed locally: every tag must
+// resolve to the root manifest, and the config and every layer of the manifest must exist, with the
+// expected size on registries. With roundtrip, every layer is downloaded again and its checksum
+// compared with the local file it was pushed from.
+func verifyPush(ctx context.Context, target oras.ReadOnlyTarget, local content.Fetcher, root ocispec.Descriptor, tags []string, roundtrip bool, logger *slog.Logger) error {
+	for _, tag := range tags {
+		desc, err := target.Resolve(ctx, tag)
+		if err != nil {
+			return fmt.Errorf("verification failed: tag %s cannot be resolved: %w", tag, err)
+		}
+		if desc.Digest != root.Digest {
+			return fmt.Errorf("verification failed: tag %s points to %s instead of the pushed manifest %s", tag, desc.Digest, root.Digest)
+		}
+	}
+
+	// FetchBytes verifies the digest of the manifest served by the registry.
+	_, manifestBytes, err := oras.FetchBytes(ctx, target, root.Digest.String(), oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return fmt.Errorf("verification failed: manifest %s cannot be fetched: %w", root.Digest, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return fmt.Errorf("verification failed: manifest %s cannot be decoded: %w", root.Digest, err)
+	}
+
+	for _, blob := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		if err := verifyBlob(ctx, target, blob); err != nil {
+			return err
+		}
+	}
+
+	if roundtrip {
+		for _, layer := range manifest.Layers {
+			if err := verifyLayerRoundtrip(ctx, target, local, layer); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Info("Verified pushed artifact", "tags", len(tags), "blobs", len(manifest.Layers)+1, "roundtrip", roundtrip)
+	return nil
+}
+
+// verifyBlob checks that a blob exists in the target. Registries answer a HEAD request on the blob
+// with its size, which must match the pushed one; local layouts only store verified blobs.
+func verifyBlob(ctx context.Context, target oras.ReadOnlyTarget, blob ocispec.Descriptor) error {
+	repo, ok := target.(*remote.Repository)
+	if !ok {
+		exists, err := target.Exists(ctx, blob)
+		if err != nil {
+			return fmt.Errorf("verification failed: blob %s cannot be checked: %w", blob.Digest, err)
+		}
+		if !exists {
+			return fmt.Errorf("verification failed: blob %s is missing", blob.Digest)
+		}
+		return nil
+	}
+
+	desc, err := repo.Blobs().Resolve(ctx, blob.Digest.String())
+	if err != nil {
+		return fmt.Errorf("verification failed: blob %s is missing: %w", blob.Digest, err)
+	}
+	if desc.Size != blob.Size {
+		return fmt.Errorf("verification failed: blob %s has size %d instead of %d", blob.Digest, desc.Size, blob.Size)
+	}
+	return nil
+}
+
+// verifyLayerRoundtrip downloads a layer and compares its checksum with the local content it was pushed from.
+func verifyLayerRoundtrip(ctx context.Context, target content.Fetcher, local content.Fetcher, layer ocispec.Descriptor) error {
+	title := layer.Annotations[ocispec.AnnotationTitle]
+
+	remoteDigest, err := digestContent(ctx, target, layer)
+	if err != nil {
+		return fmt.Errorf("verification failed: layer %s cannot be downloaded: %w", title, err)
+	}
+	localDigest, err := digestContent(ctx, local, layer)
+	if err != nil {
+		return fmt.Errorf("verification failed: local content of %s cannot be read: %w", title, err)
+	}
+
+	if remoteDigest != localDigest || remoteDigest != layer.Digest {
+		return fmt.Errorf("verification failed: layer %s downloaded with checksum %s, local file has %s", title, remoteDigest, localDigest)
+	}
+	return nil
+}
+
+// digestContent computes the digest of the content fetched for a descriptor, without verifying it,
+// so that a mismatch is reported with both checksums.
+func digestContent(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor) (digest.Digest, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	digester := desc.Digest.Algorithm().Digester()
+	if _, err := io.Copy(digester.Hash(), rc); err != nil {
+		return "", err
+	}
+	return digester.Digest(), nil
+}
@@ -2,13 +2,50 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
 
+	"github.com/flacatus/oras-puller/cmd/cat"
+	"github.com/flacatus/oras-puller/cmd/diff"
 	"github.com/flacatus/oras-puller/cmd/download"
+	"github.com/flacatus/oras-puller/cmd/inspect"
+	"github.com/flacatus/oras-puller/cmd/list"
+	"github.com/flacatus/oras-puller/cmd/login"
+	"github.com/flacatus/oras-puller/cmd/mirror"
+	"github.com/flacatus/oras-puller/cmd/prune"
+	"github.com/flacatus/oras-puller/cmd/search"
+	"github.com/flacatus/oras-puller/cmd/serve"
 	"github.com/flacatus/oras-puller/cmd/upload"
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	"github.com/flacatus/oras-puller/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
+// globalOptions holds the flags shared by every subcommand.
+type globalOptions struct {
+	// logLevel is the minimum level of the emitted log records: debug, info, warn or error.
+	logLevel string
+
+	// logFormat selects the log output format: text or json.
+	logFormat string
+
+	// registryConfig is the registry configuration file holding the credentials, instead of the Docker one.
+	registryConfig string
+
+	// username authenticates to usernameRegistry instead of its stored credentials, with the password read from stdin.
+	username string
+
+	// usernameRegistry is the registry username authenticates to (default: quay.io).
+	usernameRegistry string
+
+	// passwordStdin reads the password of the username from stdin.
+	passwordStdin bool
+}
+
+var globalOpts = &globalOptions{}
+
 func main() {
 	// Create the root command
 	rootCmd := &cobra.Command{
@@ -17,8 +54,24 @@ func main() {
 		Long: `
 Konflux OCI Artifacts is a CLI tool designed to help users manage OCI artifact storage.
 It supports operations such as uploading, downloading, and listing OCI artifacts.`,
+		// Configure the default structured logger before any subcommand runs
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			logger, err := logging.New(os.Stderr, globalOpts.logLevel, globalOpts.logFormat)
+			if err != nil {
+				return err
+			}
+			slog.SetDefault(logger)
+			return configureCredentials(cmd.InOrStdin())
+		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&globalOpts.logLevel, "log-level", "info", "Minimum log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&globalOpts.logFormat, "log-format", logging.FormatText, "Log output format: text or json")
+	rootCmd.PersistentFlags().StringVar(&globalOpts.registryConfig, "registry-config", "", "Registry configuration file holding the credentials, such as a podman auth.json or a .dockerconfigjson secret (default: the Docker configuration)")
+	rootCmd.PersistentFlags().StringVar(&globalOpts.username, "username", "", "Username to authenticate to the registry of --username-registry with, instead of its stored credentials")
+	rootCmd.PersistentFlags().StringVar(&globalOpts.usernameRegistry, "username-registry", "", "Registry host the credentials of --username are sent to, the other registries keep their stored credentials (default: quay.io)")
+	rootCmd.PersistentFlags().BoolVar(&globalOpts.passwordStdin, "password-stdin", false, "Read the password or token of --username from stdin")
+
 	// Custom Help function for the root command
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		fmt.Println(`
@@ -29,28 +82,121 @@ Usage:
 
 Available Commands:
   upload      Upload an artifact to OCI storage
+  append      Append files to an existing artifact tag
+  list        List the artifact tags of a repository
+  inspect     Show the manifest, layers and annotations of an artifact
+  cat         Print a single file of an artifact
+  diff        Compare the manifests and files of two artifacts
+  search      Search the files of recent artifacts for a regular expression
+  prune       Delete the tags of a repository according to retention policies
+  copy        Copy an artifact between repositories and layouts
+  mirror      Mirror the tags of Quay repositories to another registry or a layout
   download    Download an artifact from OCI storage
+  serve       Browse the downloaded artifacts in a web browser
+  login       Log in to a registry
+  logout      Log out from a registry
 
 Examples:
   Upload:
-    konflux-oci-artifacts upload --file=myartifact.tar --dest=oci://myrepo
+    konflux-oci-artifacts upload --dest=quay.io/org/repo:tag myartifact.tar ./folder
+
+  Append:
+    konflux-oci-artifacts append --dest=quay.io/org/repo:tag --on-conflict=replace ./folder
+
+  List:
+    konflux-oci-artifacts list quay.io/org/repo --since 1d --annotation org.opencontainers.image.revision
+
+  Inspect:
+    konflux-oci-artifacts inspect quay.io/org/repo:tag --output json
+
+  Cat:
+    konflux-oci-artifacts cat quay.io/org/repo:tag results/junit.xml
+
+  Diff:
+    konflux-oci-artifacts diff quay.io/org/repo:nightly-1 quay.io/org/repo:nightly-2 --unified
+
+  Search:
+    konflux-oci-artifacts search 'panic: runtime error' --repos quay.io/org/repo1 quay.io/org/repo2 --since 3d
+
+  Prune:
+    konflux-oci-artifacts prune quay.io/org/repo --older-than 14d --keep-last 10 --delete
+
+  Copy:
+    konflux-oci-artifacts copy quay.io/org/e2e:tag quay.io/org/archive:tag --referrers
+
+  Mirror:
+    konflux-oci-artifacts mirror --repos quay.io/org/repo1 quay.io/org/repo2 --to localhost:5000 --since 2d
 
   Download:
     konflux-oci-artifacts download --repo=oci://myrepo:tag
     konflux-oci-artifacts download --repos oci://repo1 oci://repo2 --since 4h
 
+  Serve:
+    konflux-oci-artifacts serve --artifacts-output ./artifacts --addr 127.0.0.1:8080
+
+  Login:
+    echo "$QUAY_TOKEN" | konflux-oci-artifacts login quay.io --username 'org+robot' --password-stdin
+
 Flags:
-  -h, --help   help for konflux-oci-artifacts
+  -h, --help              help for konflux-oci-artifacts
+      --log-level         Minimum log level: debug, info, warn or error (default: info)
+      --log-format        Log output format: text or json (default: text)
+      --registry-config   Registry configuration file holding the credentials (default: the Docker configuration)
+      --username          Username to authenticate to the registry of --username-registry with, instead of its stored credentials
+      --username-registry Registry host the credentials of --username are sent to (default: quay.io)
+      --password-stdin    Read the password or token of --username from stdin
 
 Use "konflux-oci-artifacts [command] --help" for more information about a command.`)
 	})
 
 	// Add subcommands
 	rootCmd.AddCommand(upload.Init())
+	rootCmd.AddCommand(upload.InitAppend())
 	rootCmd.AddCommand(download.Init())
+	rootCmd.AddCommand(list.Init())
+	rootCmd.AddCommand(inspect.Init())
+	rootCmd.AddCommand(cat.Init())
+	rootCmd.AddCommand(diff.Init())
+	rootCmd.AddCommand(search.Init())
+	rootCmd.AddCommand(prune.Init())
+	rootCmd.AddCommand(mirror.InitCopy())
+	rootCmd.AddCommand(mirror.Init())
+	rootCmd.AddCommand(serve.Init())
+	rootCmd.AddCommand(login.Init())
+	rootCmd.AddCommand(login.InitLogout())
 
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatalf("ERROR: %v", err)
+		slog.Error("Command failed", "error", oci.ClassifyAuthError(err))
+		os.Exit(1)
 	}
 }
+
+// configureCredentials sets the credentials of the remote repositories from the global flags,
+// reading the password from stdin when requested.
+func configureCredentials(stdin io.Reader) error {
+	credentialOptions := oci.CredentialOptions{
+		RegistryConfig: globalOpts.registryConfig,
+		Username:       globalOpts.username,
+		Registry:       globalOpts.usernameRegistry,
+	}
+
+	if globalOpts.passwordStdin {
+		if globalOpts.username == "" {
+			return fmt.Errorf("the --password-stdin flag requires the --username flag")
+		}
+		password, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		credentialOptions.Password = strings.TrimRight(string(password), "\r\n")
+	} else if globalOpts.username != "" {
+		return fmt.Errorf("the --username flag requires the --password-stdin flag")
+	}
+	if globalOpts.usernameRegistry != "" && globalOpts.username == "" {
+		return fmt.Errorf("the --username-registry flag requires the --username flag")
+	}
+
+	oci.SetCredentialOptions(credentialOptions)
+	return nil
+}
@@ -0,0 +1,208 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+)
+
+// ErrFileNotFound is returned by CatFile when no layer of the artifact contains the path.
+var ErrFileNotFound = errors.New("file not found in artifact")
+
+// ArtifactFile is a file of an artifact, at the path it is extracted to by the download command.
+type ArtifactFile struct {
+	// Path is the slash-separated path of the file in the artifact.
+	Path string `json:"path"`
+
+	// Size is the size of the file in bytes, zero for directories.
+	Size int64 `json:"size"`
+
+	// Dir is true for directories.
+	Dir bool `json:"dir,omitempty"`
+
+	// Layer is the digest of the layer holding the file.
+	Layer string `json:"layer"`
+}
+
+// ListFiles lists the files of the artifact referenced by a tag or digest. Archive layers are
+// streamed to read their entries, nothing is written to disk. Large layers may take longer than
+// blobTimeout to stream, so only ctx bounds the listing.
+func (c *Controller) ListFiles(ctx context.Context, repo, reference string) ([]ArtifactFile, error) {
+	src, manifest, err := c.openArtifact(ctx, repo, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ArtifactFile
+	for _, layer := range manifest.Layers {
+		err := c.walkLayer(ctx, src, layer, func(name string, header *tar.Header, _ io.Reader) (bool, error) {
+			file := ArtifactFile{Path: name, Layer: layer.Digest.String()}
+			if header == nil {
+				file.Size = layer.Size
+			} else {
+				file.Size, file.Dir = header.Size, header.Typeflag == tar.TypeDir
+				if file.Dir {
+					file.Size = 0
+				}
+			}
+			files = append(files, file)
+			return false, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// CatFile streams the file at the given path of the artifact to w. The layer titled with the path
+// is streamed as is; otherwise the archive layers are scanned for the entry, the layers titled
+// with the first element of the path first. Only the layers up to the one holding the file are read.
+// Like for ListFiles, only ctx bounds the streaming.
+func (c *Controller) CatFile(ctx context.Context, repo, reference, filePath string, w io.Writer) error {
+	filePath = cleanArtifactPath(filePath)
+	src, manifest, err := c.openArtifact(ctx, repo, reference)
+	if err != nil {
+		return err
+	}
+
+	root, _, _ := strings.Cut(filePath, "/")
+	var preferred, others []ocispec.Descriptor
+	for _, layer := range manifest.Layers {
+		title := cleanArtifactPath(layer.Annotations[ocispec.AnnotationTitle])
+		switch {
+		case title == filePath && layer.Annotations[AnnotationUnpack] != "true":
+			return streamBlob(ctx, src, layer, w)
+		case title == root:
+			preferred = append(preferred, layer)
+		default:
+			others = append(others, layer)
+		}
+	}
+
+	for _, layer := range append(preferred, others...) {
+		found := false
+		err := c.walkLayer(ctx, src, layer, func(name string, header *tar.Header, r io.Reader) (bool, error) {
+			if header == nil || name != filePath {
+				return false, nil
+			}
+			found = true
+			switch header.Typeflag {
+			case tar.TypeReg:
+				if _, err := io.Copy(w, r); err != nil {
+					return true, fmt.Errorf("failed to stream %s: %w", filePath, err)
+				}
+				return true, nil
+			case tar.TypeSymlink:
+				return true, fmt.Errorf("%s is a symbolic link to %s", filePath, header.Linkname)
+			default:
+				return true, fmt.Errorf("%s is not a regular file", filePath)
+			}
+		})
+		if err != nil || found {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+}
+
+// openArtifact sets up the source of the repository and fetches the manifest of the artifact.
+func (c *Controller) openArtifact(ctx context.Context, repo, reference string) (oras.ReadOnlyTarget, ocispec.Manifest, error) {
+	src, err := c.setupSource(repo)
+	if err != nil {
+		return nil, ocispec.Manifest{}, err
+	}
+	_, manifest, err := c.fetchManifest(ctx, src, reference)
+	if err != nil {
+		return nil, ocispec.Manifest{}, err
+	}
+	return src, manifest, nil
+}
+
+// walkLayer calls fn for every file of a layer until fn returns true. Archives, recognized by the
+// unpack annotation or the gzip header like on download, are streamed entry by entry, and fn
+// receives the tar header and the content of each entry. Other layers are a single file named
+// after their title, reported with a nil header, and skipped when they have no title.
+func (c *Controller) walkLayer(ctx context.Context, src content.Fetcher, layer ocispec.Descriptor, fn func(name string, header *tar.Header, r io.Reader) (bool, error)) error {
+	rc, err := src.Fetch(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("failed to fetch layer %s: %w", layer.Digest, err)
+	}
+	defer rc.Close()
+
+	reader := bufio.NewReader(rc)
+	magic, _ := reader.Peek(2)
+	isGzip := len(magic) == 2 && magic[0] == 0x1F && magic[1] == 0x8B
+	if !isGzip && layer.Annotations[AnnotationUnpack] != "true" {
+		title := cleanArtifactPath(layer.Annotations[ocispec.AnnotationTitle])
+		if title == "" {
+			return nil
+		}
+		_, err := fn(title, nil, reader)
+		return err
+	}
+
+	var stream io.Reader = reader
+	if isGzip {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read layer %s: %w", layer.Digest, err)
+		}
+		defer gzipReader.Close()
+		stream = gzipReader
+	}
+
+	tarReader := tar.NewReader(stream)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header of layer %s: %w", layer.Digest, err)
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		done, err := fn(cleanArtifactPath(header.Name), header, tarReader)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// streamBlob copies a layer to w and verifies its digest once it has been read entirely.
+func streamBlob(ctx context.Context, src content.Fetcher, layer ocispec.Descriptor, w io.Writer) error {
+	rc, err := src.Fetch(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("failed to fetch layer %s: %w", layer.Digest, err)
+	}
+	defer rc.Close()
+
+	verifier := content.NewVerifyReader(rc, layer)
+	if _, err := io.Copy(w, verifier); err != nil {
+		return fmt.Errorf("failed to stream layer %s: %w", layer.Digest, err)
+	}
+	if err := verifier.Verify(); err != nil {
+		return fmt.Errorf("failed to verify layer %s: %w", layer.Digest, err)
+	}
+	return nil
+}
+
+// cleanArtifactPath normalizes a path of an artifact: slash-separated, relative and without a trailing slash.
+func cleanArtifactPath(name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,107 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// newFilesController returns a controller reading an artifact made of a file layer and a directory layer.
+func newFilesController(t *testing.T) *Controller {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+
+	file, err := oras.PushBytes(ctx, store, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+	file.Annotations = map[string]string{ocispec.AnnotationTitle: "a.txt"}
+
+	dir := filepath.Join(t.TempDir(), "results")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "junit.xml"), []byte("<testsuites/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if _, err := PackDirectory(dir, &archive, PathFilter{}); err != nil {
+		t.Fatalf("failed to pack directory: %v", err)
+	}
+	layer, err := oras.PushBytes(ctx, store, ocispec.MediaTypeImageLayerGzip, archive.Bytes())
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+	layer.Annotations = map[string]string{ocispec.AnnotationTitle: "results", AnnotationUnpack: "true"}
+
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{file, layer},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	if err := store.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatalf("failed to tag manifest: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.Source = store
+	return controller
+}
+
+// TestListFiles verifies that file layers and the entries of directory layers are listed.
+func TestListFiles(t *testing.T) {
+	files, err := newFilesController(t).ListFiles(context.Background(), "org/repo", "v1")
+	if err != nil {
+		t.Fatalf("failed to list files: %v", err)
+	}
+
+	expected := map[string]int64{"a.txt": 5, "results": 0, "results/sub": 0, "results/sub/junit.xml": 13}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %+v", len(expected), files)
+	}
+	for _, file := range files {
+		if size, ok := expected[file.Path]; !ok || size != file.Size {
+			t.Errorf("unexpected file %+v", file)
+		}
+	}
+}
+
+// TestCatFile verifies that a single file is streamed from file layers and directory layers.
+func TestCatFile(t *testing.T) {
+	controller := newFilesController(t)
+
+	cases := map[string]string{
+		"a.txt":                    "hello",
+		"results/sub/junit.xml":    "<testsuites/>",
+		"/results/./sub/junit.xml": "<testsuites/>",
+	}
+	for path, expected := range cases {
+		var out bytes.Buffer
+		if err := controller.CatFile(context.Background(), "org/repo", "v1", path, &out); err != nil {
+			t.Errorf("failed to read %s: %v", path, err)
+			continue
+		}
+		if out.String() != expected {
+			t.Errorf("expected %q for %s, got %q", expected, path, out.String())
+		}
+	}
+
+	if err := controller.CatFile(context.Background(), "org/repo", "v1", "results/missing.xml", &bytes.Buffer{}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+	if err := controller.CatFile(context.Background(), "org/repo", "v1", "results/sub", &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a directory")
+	}
+}
@@ -0,0 +1,203 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// Kinds of authentication failures reported by AuthError.
+const (
+	// AuthMissingCredentials is reported when a registry requires credentials and none were sent.
+	AuthMissingCredentials = "missing credentials"
+
+	// AuthInvalidCredentials is reported when a registry rejects the credentials, such as a wrong or expired token.
+	AuthInvalidCredentials = "invalid credentials"
+
+	// AuthInsufficientScope is reported when the credentials are valid but do not grant access to the repository.
+	AuthInsufficientScope = "insufficient scope"
+)
+
+// AuthError is an authentication failure with the registry and the credential source that was tried.
+type AuthError struct {
+	// Kind is the kind of failure: AuthMissingCredentials, AuthInvalidCredentials or AuthInsufficientScope.
+	Kind string
+
+	// Registry is the host of the registry that rejected the request.
+	Registry string
+
+	// Source describes where the credentials were looked up.
+	Source string
+
+	// Err is the error returned by the registry.
+	Err error
+}
+
+// Error describes the failure and how to fix it.
+func (e *AuthError) Error() string {
+	var message string
+	switch e.Kind {
+	case AuthMissingCredentials:
+		message = fmt.Sprintf("registry %s requires authentication but no credentials for it were found in %s: "+
+			"log in with 'konflux-oci-artifacts login %s', point --registry-config to a file holding them, "+
+			"or pass --username and --password-stdin with --username-registry %s", e.Registry, e.Source, e.Registry, e.Registry)
+	case AuthInvalidCredentials:
+		message = fmt.Sprintf("registry %s rejected the credentials from %s: the password or token is wrong or expired, "+
+			"log in again with 'konflux-oci-artifacts login %s'", e.Registry, e.Source, e.Registry)
+	default:
+		message = fmt.Sprintf("the credentials from %s are not allowed to access this repository of %s: "+
+			"grant the account read access, and write access to push, or use another account", e.Source, e.Registry)
+	}
+	return fmt.Sprintf("%s: %v", message, e.Err)
+}
+
+// Unwrap returns the error returned by the registry.
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// credentialSource records where the credentials of the registries come from and the registries
+// credentials were sent to, to classify the authentication failures.
+var credentialSource = &credentialTracker{}
+
+// credentialTracker is safe for concurrent use by the clients of the remote repositories.
+type credentialTracker struct {
+	mu      sync.Mutex
+	source  string
+	sources map[string]string
+	sent    map[string]bool
+}
+
+// setSource sets the description of the credential source of the registries.
+func (t *credentialTracker) setSource(source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.source = source
+	t.sources = nil
+}
+
+// setRegistrySource sets the description of the credential source of a single registry.
+func (t *credentialTracker) setRegistrySource(registry, source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sources == nil {
+		t.sources = make(map[string]string)
+	}
+	t.sources[registry] = source
+}
+
+// record remembers whether credentials were found for the registry.
+func (t *credentialTracker) record(registry string, cred auth.Credential) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sent == nil {
+		t.sent = make(map[string]bool)
+	}
+	t.sent[registry] = t.sent[registry] || cred != auth.EmptyCredential
+}
+
+// lookup returns the description of the credential source and whether credentials were sent to
+// the registry. Token services may live on another host, so an unknown host reports whether
+// credentials were sent to any registry.
+func (t *credentialTracker) lookup(registry string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	source, ok := t.sources[registry]
+	if !ok {
+		source = t.source
+	}
+	if source == "" {
+		source = "the Docker configuration"
+	}
+	if sent, ok := t.sent[registry]; ok {
+		return source, sent
+	}
+	for _, sent := range t.sent {
+		if sent {
+			return source, true
+		}
+	}
+	return source, false
+}
+
+// anonymousRegistry returns a registry no credentials were found for, or an empty string.
+func (t *credentialTracker) anonymousRegistry() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var registries []string
+	for registry, sent := range t.sent {
+		if !sent {
+			registries = append(registries, registry)
+		}
+	}
+	sort.Strings(registries)
+	if len(registries) == 0 {
+		return ""
+	}
+	return registries[0]
+}
+
+// ClassifyAuthError turns the authentication failures of registries into an AuthError naming the
+// registry and the credential source that was tried. Other errors are returned unchanged.
+//
+// A registry that requests credentials it did not get reports missing credentials. A token service
+// refusing the credentials, or a registry with basic authentication, reports invalid credentials,
+// and a registry refusing the token issued for them reports an insufficient scope.
+func ClassifyAuthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return err
+	}
+
+	// Registries with basic authentication fail before any request is sent without credentials
+	if errors.Is(err, auth.ErrBasicCredentialNotFound) {
+		registry := credentialSource.anonymousRegistry()
+		if registry == "" {
+			return err
+		}
+		source, _ := credentialSource.lookup(registry)
+		return &AuthError{Kind: AuthMissingCredentials, Registry: registry, Source: source, Err: err}
+	}
+
+	var response *errcode.ErrorResponse
+	if !errors.As(err, &response) || response.URL == nil {
+		return err
+	}
+	if response.StatusCode != http.StatusUnauthorized && response.StatusCode != http.StatusForbidden {
+		return err
+	}
+
+	registry := response.URL.Host
+	source, sent := credentialSource.lookup(registry)
+	kind := AuthMissingCredentials
+	if sent {
+		query := response.URL.Query()
+		scheme, _ := authCache.GetScheme(context.Background(), registry)
+		switch {
+		case query.Has("service") || query.Has("scope") || response.Method == http.MethodPost && !isRegistryAPI(response.URL.Path):
+			// Token services reject the credentials themselves
+			kind = AuthInvalidCredentials
+		case response.StatusCode == http.StatusUnauthorized && scheme == auth.SchemeBasic:
+			// With basic authentication the registry checks the credentials on every request
+			kind = AuthInvalidCredentials
+		default:
+			kind = AuthInsufficientScope
+		}
+	}
+	return &AuthError{Kind: kind, Registry: registry, Source: source, Err: err}
+}
+
+// isRegistryAPI reports whether the path is one of the repository endpoints of the distribution API.
+func isRegistryAPI(path string) bool {
+	return strings.HasPrefix(path, "/v2/") && path != "/v2/auth" && path != "/v2/token"
+}
@@ -0,0 +1,126 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// errorResponse returns a registry error response for the URL, wrapped like the errors of the remote repositories.
+func errorResponse(t *testing.T, method, rawURL string, status int) error {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Errorf("failed to fetch manifest for tag v1: %w", &errcode.ErrorResponse{Method: method, URL: u, StatusCode: status})
+}
+
+// TestClassifyAuthError verifies the kind of failure reported for the authentication errors.
+func TestClassifyAuthError(t *testing.T) {
+	tests := []struct {
+		name     string
+		sent     bool
+		err      func(t *testing.T) error
+		expected string
+	}{
+		{
+			name: "anonymous request denied",
+			err: func(t *testing.T) error {
+				return errorResponse(t, http.MethodGet, "https://quay.io/v2/org/repo/manifests/v1", 401)
+			},
+			expected: AuthMissingCredentials,
+		},
+		{
+			name: "token service rejecting the credentials",
+			sent: true,
+			err: func(t *testing.T) error {
+				return errorResponse(t, http.MethodGet, "https://quay.io/v2/auth?scope=repository%3Aorg%2Frepo%3Apull&service=quay.io", 401)
+			},
+			expected: AuthInvalidCredentials,
+		},
+		{
+			name: "token without access to the repository",
+			sent: true,
+			err: func(t *testing.T) error {
+				return errorResponse(t, http.MethodPut, "https://quay.io/v2/org/repo/manifests/v1", 403)
+			},
+			expected: AuthInsufficientScope,
+		},
+		{
+			name: "basic credential not found",
+			err: func(t *testing.T) error {
+				return fmt.Errorf("GET %q: %w", "https://quay.io/v2/", auth.ErrBasicCredentialNotFound)
+			},
+			expected: AuthMissingCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := credentialSource
+			credentialSource = &credentialTracker{source: "the registry configuration /tmp/auth.json"}
+			t.Cleanup(func() { credentialSource = previous })
+
+			cred := auth.EmptyCredential
+			if tt.sent {
+				cred = auth.Credential{Username: "robot", Password: "token"}
+			}
+			credentialSource.record("quay.io", cred)
+
+			var authErr *AuthError
+			if err := ClassifyAuthError(tt.err(t)); !errors.As(err, &authErr) {
+				t.Fatalf("expected an AuthError, got %v", err)
+			}
+			if authErr.Kind != tt.expected || authErr.Registry != "quay.io" {
+				t.Errorf("expected %s for quay.io, got %s for %s", tt.expected, authErr.Kind, authErr.Registry)
+			}
+			if !strings.Contains(authErr.Error(), "/tmp/auth.json") {
+				t.Errorf("expected the message to name the credential source, got %s", authErr.Error())
+			}
+		})
+	}
+}
+
+// TestClassifyAuthErrorKeepsOtherErrors verifies that errors unrelated to authentication are returned unchanged.
+func TestClassifyAuthErrorKeepsOtherErrors(t *testing.T) {
+	notFound := errorResponse(t, http.MethodGet, "https://quay.io/v2/org/repo/manifests/v1", 404)
+	if err := ClassifyAuthError(notFound); err != notFound {
+		t.Errorf("expected the error unchanged, got %v", err)
+	}
+	if err := ClassifyAuthError(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+// TestClassifyAuthErrorBasic verifies that a registry with basic authentication rejecting the
+// credentials reports invalid credentials.
+func TestClassifyAuthErrorBasic(t *testing.T) {
+	previous := credentialSource
+	credentialSource = &credentialTracker{source: "the --username flag"}
+	t.Cleanup(func() { credentialSource = previous })
+
+	const registry = "basic.example.com"
+	credentialSource.record(registry, auth.Credential{Username: "robot", Password: "wrong"})
+	_, err := authCache.Set(context.Background(), registry, auth.SchemeBasic, "", func(context.Context) (string, error) {
+		return "cm9ib3Q6d3Jvbmc=", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var authErr *AuthError
+	if err := ClassifyAuthError(errorResponse(t, http.MethodGet, "https://"+registry+"/v2/org/repo/manifests/v1", 401)); !errors.As(err, &authErr) {
+		t.Fatalf("expected an AuthError, got %v", err)
+	}
+	if authErr.Kind != AuthInvalidCredentials {
+		t.Errorf("expected %s, got %s", AuthInvalidCredentials, authErr.Kind)
+	}
+}
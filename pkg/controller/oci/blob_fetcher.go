@@ -0,0 +1,275 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+)
+
+const (
+	// partialBlobDir is the directory inside the OCI cache holding interrupted blob downloads.
+	partialBlobDir = "partial"
+
+	// blobFetchConcurrency limits the number of layers downloaded in parallel.
+	blobFetchConcurrency = 3
+
+	// staleBlobLock is the age after which the lock file of a partial blob is considered left over
+	// by a process that died. Holders refresh their lock file four times within this duration.
+	staleBlobLock = 2 * time.Minute
+
+	// blobLockPollInterval is the interval at which a locked partial blob is checked again.
+	blobLockPollInterval = 200 * time.Millisecond
+)
+
+// blobMutexes serializes the downloads of the same blob within the process, by partial blob path.
+var blobMutexes sync.Map
+
+// fetchLayers downloads the layers of the manifest that are missing from the local store.
+// Downloads are written to partial files named after the expected digest, so that an interrupted
+// run resumes with an HTTP Range request instead of starting over from zero.
+func (c *Controller) fetchLayers(ctx context.Context, src *trackedSource, manifest ocispec.Manifest) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(manifest.Layers))
+	sem := make(chan struct{}, blobFetchConcurrency)
+
+	// A layer may be referenced several times by the same manifest, it is fetched once
+	seen := make(map[string]bool, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		if seen[layer.Digest.String()] {
+			continue
+		}
+		seen[layer.Digest.String()] = true
+
+		wg.Add(1)
+		go func(layer ocispec.Descriptor) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := c.fetchLayer(ctx, src, layer); err != nil {
+				errs <- err
+			}
+		}(layer)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+// fetchLayer stores a single layer in the local store and reports it as fetched or cached.
+func (c *Controller) fetchLayer(ctx context.Context, src *trackedSource, layer ocispec.Descriptor) error {
+	exists, err := c.Store.Exists(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("failed to check blob %s in the local store: %w", layer.Digest, err)
+	}
+
+	event := layerEvent(progress.LayerFetched, src.repo, src.tag, layer)
+	if !exists {
+		if err := c.fetchBlobResumable(ctx, src.ReadOnlyTarget, layer, event); err != nil {
+			return err
+		}
+	}
+
+	event.Cached = exists
+	src.markFetched(event)
+	return nil
+}
+
+// fetchBlobResumable downloads the blob described by desc into the blob directory of the store.
+// Bytes already present in the partial file are kept and the download continues at their offset,
+// provided that the registry supports range requests. The complete file is verified against the
+// expected digest before it is moved into the store, at which point the blob is marked present.
+// Concurrent downloads of the same blob, from this process or another one sharing the cache, are
+// serialized, and the later ones find the blob in the store.
+func (c *Controller) fetchBlobResumable(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor, event progress.Event) error {
+	partialPath := c.partialBlobPath(desc)
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		return fmt.Errorf("failed to create partial blob directory: %w", err)
+	}
+
+	unlock, err := lockPartialBlob(ctx, partialPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	blobPath := c.blobPath(desc)
+	if _, err := os.Stat(blobPath); err == nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open partial blob %s: %w", partialPath, err)
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek partial blob %s: %w", partialPath, err)
+	}
+	if offset > desc.Size {
+		if offset, err = truncate(file); err != nil {
+			return err
+		}
+	}
+
+	if offset < desc.Size {
+		if err := c.downloadBlob(ctx, fetcher, desc, file, offset, event); err != nil {
+			return err
+		}
+	}
+
+	if err := verifyBlob(file, desc); err != nil {
+		// A corrupted partial file can never complete, so start over on the next run.
+		os.Remove(partialPath)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(partialPath, blobPath); err != nil {
+		return fmt.Errorf("failed to move blob %s into the store: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+// downloadBlob appends the remaining bytes of the blob to file, starting at offset.
+// When the registry cannot serve the requested range, the download restarts from the beginning.
+func (c *Controller) downloadBlob(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor, file *os.File, offset int64, event progress.Event) error {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("failed to fetch blob %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	if offset > 0 {
+		seeker, ok := rc.(io.Seeker)
+		if ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+		}
+		if !ok || err != nil {
+			c.Logger.Warn("Registry does not support resuming the blob download, restarting from zero",
+				"repo", event.Repo, "tag", event.Tag, "digest", desc.Digest.String(), "error", err)
+			if offset, err = truncate(file); err != nil {
+				return err
+			}
+		} else {
+			c.Logger.Info("Resuming blob download", "repo", event.Repo, "tag", event.Tag, "digest", desc.Digest.String(), "offset", offset)
+		}
+	}
+
+	reader := progress.NewReadCloser(rc, c.Progress, event, offset)
+	if _, err := io.Copy(file, io.LimitReader(reader, desc.Size-offset)); err != nil {
+		return fmt.Errorf("failed to download blob %s: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+// lockPartialBlob takes the lock of a partial blob, held by a single download at a time, and
+// returns the function releasing it. Within the process the lock is a mutex; across processes it
+// is a lock file created exclusively next to the partial file, whose modification time is
+// refreshed while the lock is held so that the lock of a process that died can be taken over.
+func lockPartialBlob(ctx context.Context, partialPath string) (func(), error) {
+	value, _ := blobMutexes.LoadOrStore(partialPath, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	lockPath := partialPath + ".lock"
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lockFile.Close()
+			break
+		}
+		if !os.IsExist(err) {
+			mu.Unlock()
+			return nil, fmt.Errorf("failed to lock partial blob %s: %w", partialPath, err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleBlobLock {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			mu.Unlock()
+			return nil, fmt.Errorf("failed to lock partial blob %s: %w", partialPath, ctx.Err())
+		case <-time.After(blobLockPollInterval):
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(staleBlobLock / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(lockPath, now, now)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		os.Remove(lockPath)
+		mu.Unlock()
+	}, nil
+}
+
+// blobPath returns the path of the blob in the store, in the directory of its digest algorithm.
+func (c *Controller) blobPath(desc ocispec.Descriptor) string {
+	return filepath.Join(c.OCIStorePath, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+}
+
+// partialBlobPath returns the path of the partial download file of the blob, named after its expected digest.
+func (c *Controller) partialBlobPath(desc ocispec.Descriptor) string {
+	return filepath.Join(c.OCIStorePath, partialBlobDir, desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+}
+
+// verifyBlob checks that the content of file matches the size and digest of desc.
+func verifyBlob(file *os.File, desc ocispec.Descriptor) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek blob %s: %w", desc.Digest, err)
+	}
+
+	verifier := desc.Digest.Verifier()
+	n, err := io.Copy(verifier, file)
+	if err != nil {
+		return fmt.Errorf("failed to read blob %s: %w", desc.Digest, err)
+	}
+	if n != desc.Size {
+		return fmt.Errorf("blob %s has size %d, expected %d", desc.Digest, n, desc.Size)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("blob %s does not match its digest", desc.Digest)
+	}
+	return nil
+}
+
+// truncate empties the file and rewinds it, returning the new offset.
+func truncate(file *os.File) (int64, error) {
+	if err := file.Truncate(0); err != nil {
+		return 0, fmt.Errorf("failed to truncate partial blob %s: %w", file.Name(), err)
+	}
+	return file.Seek(0, io.SeekStart)
+}
@@ -0,0 +1,223 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// newBlobServer serves a single blob with range support and records the Range headers it received.
+func newBlobServer(t *testing.T, blob []byte, ranges *[]string) *remote.Repository {
+	var mu sync.Mutex
+	dgst := digest.FromBytes(blob)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/test/repo/blobs/"+dgst.String() {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "blob", time.Time{}, bytes.NewReader(blob))
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := remote.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/test/repo")
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	repo.PlainHTTP = true
+	return repo
+}
+
+// TestFetchBlobResumable verifies that a partial download is resumed with a range request
+// and that the verified blob is moved into the store.
+func TestFetchBlobResumable(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 1000))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+
+	var ranges []string
+	repo := newBlobServer(t, blob, &ranges)
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	// Simulate an interrupted run that stored the first half of the blob.
+	partialPath := controller.partialBlobPath(desc)
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		t.Fatalf("failed to create partial directory: %v", err)
+	}
+	if err := os.WriteFile(partialPath, blob[:5000], 0644); err != nil {
+		t.Fatalf("failed to write partial blob: %v", err)
+	}
+
+	if err := controller.fetchBlobResumable(context.Background(), repo, desc, progress.Event{}); err != nil {
+		t.Fatalf("failed to fetch blob: %v", err)
+	}
+
+	expectedRange := fmt.Sprintf("bytes=5000-%d", len(blob)-1)
+	if len(ranges) != 2 || ranges[1] != expectedRange {
+		t.Errorf("expected a resumed request with range %q, got %v", expectedRange, ranges)
+	}
+
+	stored, err := os.ReadFile(controller.blobPath(desc))
+	if err != nil {
+		t.Fatalf("blob was not moved into the store: %v", err)
+	}
+	if !bytes.Equal(stored, blob) {
+		t.Errorf("stored blob does not match the served content")
+	}
+	if _, err := os.Stat(partialPath); !os.IsNotExist(err) {
+		t.Errorf("expected partial file to be removed, got %v", err)
+	}
+}
+
+// TestFetchBlobResumableCorruptPartial verifies that a partial file with wrong content is
+// rejected by the digest check and discarded so that the next run starts over.
+func TestFetchBlobResumableCorruptPartial(t *testing.T) {
+	blob := []byte(strings.Repeat("abcdefghij", 100))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+
+	var ranges []string
+	repo := newBlobServer(t, blob, &ranges)
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	partialPath := controller.partialBlobPath(desc)
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		t.Fatalf("failed to create partial directory: %v", err)
+	}
+	if err := os.WriteFile(partialPath, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("failed to write partial blob: %v", err)
+	}
+
+	if err := controller.fetchBlobResumable(context.Background(), repo, desc, progress.Event{}); err == nil {
+		t.Fatalf("expected a digest verification error")
+	}
+	if _, err := os.Stat(partialPath); !os.IsNotExist(err) {
+		t.Errorf("expected corrupted partial file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(controller.blobPath(desc)); !os.IsNotExist(err) {
+		t.Errorf("expected corrupted blob not to be marked present, got %v", err)
+	}
+}
+
+// TestFetchBlobResumableConcurrent verifies that concurrent downloads of the same blob are
+// serialized, the later ones finding the blob downloaded by the first one in the store.
+func TestFetchBlobResumableConcurrent(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 10000))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+
+	var ranges []string
+	repo := newBlobServer(t, blob, &ranges)
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- controller.fetchBlobResumable(context.Background(), repo, desc, progress.Event{})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to fetch blob: %v", err)
+		}
+	}
+	if len(ranges) != 1 {
+		t.Errorf("expected the blob to be downloaded once, got %d requests", len(ranges))
+	}
+	stored, err := os.ReadFile(controller.blobPath(desc))
+	if err != nil {
+		t.Fatalf("blob was not moved into the store: %v", err)
+	}
+	if !bytes.Equal(stored, blob) {
+		t.Errorf("stored blob does not match the served content")
+	}
+}
+
+// TestFetchBlobResumableSHA512 verifies that a blob with a sha512 digest is stored in the
+// directory of its algorithm, where the store finds it.
+func TestFetchBlobResumableSHA512(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 100))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.SHA512.FromBytes(blob), Size: int64(len(blob))}
+
+	store := memory.New()
+	if err := store.Push(context.Background(), desc, bytes.NewReader(blob)); err != nil {
+		t.Fatalf("failed to push blob: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	if err := controller.fetchBlobResumable(context.Background(), store, desc, progress.Event{}); err != nil {
+		t.Fatalf("failed to fetch blob: %v", err)
+	}
+
+	exists, err := controller.Store.Exists(context.Background(), desc)
+	if err != nil {
+		t.Fatalf("failed to check blob: %v", err)
+	}
+	if !exists {
+		t.Errorf("expected the sha512 blob to be present in the store")
+	}
+}
+
+// TestLockPartialBlob verifies that the lock file of another process is waited for, unless it
+// was left over by a process that stopped refreshing it.
+func TestLockPartialBlob(t *testing.T) {
+	partialPath := filepath.Join(t.TempDir(), "blob")
+	lockPath := partialPath + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0644); err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := lockPartialBlob(ctx, partialPath); err == nil {
+		t.Fatalf("expected the lock held by another process to be waited for")
+	}
+
+	stale := time.Now().Add(-2 * staleBlobLock)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatalf("failed to age lock file: %v", err)
+	}
+	unlock, err := lockPartialBlob(context.Background(), partialPath)
+	if err != nil {
+		t.Fatalf("failed to take over stale lock: %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("expected lock file to be removed, got %v", err)
+	}
+}
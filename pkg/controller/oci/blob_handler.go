@@ -3,7 +3,6 @@ package oci
 import (
 	"archive/tar"
 	"compress/gzip"
-	"context"
 	"fmt"
 	"io"
 	"os"
@@ -11,10 +10,9 @@ import (
 	"strings"
 	"sync"
 	"time"
-)
 
-const (
-	extractTimeout = 1 * time.Minute
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // Extracts tar.gz files to a specified destination.
@@ -38,8 +36,11 @@ func (c *Controller) extractTarGz(gzipStream io.Reader, dest string) error {
 			return fmt.Errorf("failed to read tar header: %w", err)
 		}
 
-		destPath := filepath.Join(dest, header.Name)
-		if err := c.handleTarEntry(header, tarReader, destPath); err != nil {
+		destPath, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := c.handleTarEntry(header, tarReader, dest, destPath); err != nil {
 			return err
 		}
 	}
@@ -47,25 +48,45 @@ func (c *Controller) extractTarGz(gzipStream io.Reader, dest string) error {
 }
 
 // Handles individual entries in the tar archive.
-// It creates directories or files as specified in the tar header.
-func (c *Controller) handleTarEntry(header *tar.Header, tarReader *tar.Reader, destPath string) error {
+// It creates directories, files or symbolic links as specified in the tar header.
+func (c *Controller) handleTarEntry(header *tar.Header, tarReader *tar.Reader, dest, destPath string) error {
 	switch header.Typeflag {
 	case tar.TypeDir:
-		return os.MkdirAll(destPath, 0755)
+		dir, err := resolveInside(dest, destPath)
+		if err != nil {
+			return err
+		}
+		return os.MkdirAll(dir, 0755)
 	case tar.TypeReg:
-		if _, err := os.Stat(destPath); err == nil {
+		if _, err := os.Lstat(destPath); err == nil {
 			return nil
 		}
-		return c.createFileFromTar(tarReader, destPath)
+		return c.createFileFromTar(tarReader, dest, destPath, os.FileMode(header.Mode).Perm())
+	case tar.TypeSymlink:
+		return c.createSymlinkFromTar(header, dest, destPath)
+	case tar.TypeXGlobalHeader:
+		return nil
 	default:
 		return fmt.Errorf("unsupported tar entry: %c", header.Typeflag)
 	}
 }
 
 // Creates a file from the tar reader.
-// It writes the contents of the tar entry to a newly created file.
-func (c *Controller) createFileFromTar(tarReader *tar.Reader, destPath string) error {
-	outFile, err := os.Create(destPath)
+// It writes the contents of the tar entry to a newly created file, creating its parent directories.
+func (c *Controller) createFileFromTar(tarReader *tar.Reader, dest, destPath string, mode os.FileMode) error {
+	outPath, err := resolveInside(dest, destPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+
+	// O_EXCL does not follow a symbolic link created by a previous entry
+	outFile, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w", destPath, err)
 	}
@@ -78,22 +99,118 @@ func (c *Controller) createFileFromTar(tarReader *tar.Reader, destPath string) e
 	return nil
 }
 
+// Creates a symbolic link from the tar header.
+// Links pointing outside of the extraction directory are rejected.
+func (c *Controller) createSymlinkFromTar(header *tar.Header, dest, destPath string) error {
+	if filepath.IsAbs(header.Linkname) {
+		return fmt.Errorf("symlink %s points to absolute path %s", header.Name, header.Linkname)
+	}
+	if _, err := os.Lstat(destPath); err == nil {
+		return nil
+	}
+	linkPath, err := resolveInside(dest, destPath)
+	if err != nil {
+		return err
+	}
+
+	// The target is relative to the directory the link is actually created in, which differs from
+	// the directory of the entry name when a parent is a link created by a previous entry
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dest, err)
+	}
+	parent := filepath.Dir(linkPath)
+	if !isInside(root, filepath.Join(parent, header.Linkname)) {
+		return fmt.Errorf("symlink %s points outside of the extraction directory: %s", header.Name, header.Linkname)
+	}
+	if target, err := filepath.EvalSymlinks(parent + string(filepath.Separator) + header.Linkname); err == nil && !isInside(root, target) {
+		return fmt.Errorf("symlink %s points outside of the extraction directory: %s", header.Name, header.Linkname)
+	}
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+	}
+	return os.Symlink(header.Linkname, linkPath)
+}
+
+// Joins a relative entry name to the destination directory.
+// It returns an error for names escaping the destination, e.g. through "../" elements.
+func safeJoin(dest, name string) (string, error) {
+	destPath := filepath.Join(dest, name)
+	if !isInside(dest, destPath) {
+		return "", fmt.Errorf("illegal path %s outside of %s", name, dest)
+	}
+	return destPath, nil
+}
+
+// Returns the path an entry joined with safeJoin is written to, with the symbolic links of its
+// parent directories resolved. Links created by previous entries can make a path escape the
+// destination on disk although its name does not, so the path is rejected when its parent resolves
+// outside of the destination. The check happens before any missing parent directory is created.
+func resolveInside(dest, destPath string) (string, error) {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dest, err)
+	}
+	root, err := filepath.EvalSymlinks(dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dest, err)
+	}
+
+	// Resolve the deepest existing parent, the missing ones are created as plain directories
+	dir, missing := filepath.Dir(destPath), []string{filepath.Base(destPath)}
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if !isInside(root, resolved) {
+				return "", fmt.Errorf("illegal path %s: %s resolves outside of %s", destPath, dir, dest)
+			}
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+		if _, err := os.Lstat(dir); err == nil {
+			return "", fmt.Errorf("illegal path %s: %s is a dangling symlink", destPath, dir)
+		}
+		if !isInside(dest, dir) {
+			return "", fmt.Errorf("illegal path %s outside of %s", destPath, dest)
+		}
+		missing = append(missing, filepath.Base(dir))
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Reports whether path is dir or one of its descendants, comparing the paths lexically.
+func isInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // Handles the extraction of individual blobs.
-// It manages concurrency with WaitGroup and semaphore for blob processing.
-func (c *Controller) HandleBlob(blobPath, outputDir string, wg *sync.WaitGroup, errors chan<- error, sem chan struct{}) {
+// It manages concurrency with WaitGroup and semaphore for blob processing,
+// and reports the given event once the blob of the layer has been extracted.
+func (c *Controller) HandleBlob(blobPath, outputDir string, layer ocispec.Descriptor, event progress.Event, wg *sync.WaitGroup, errors chan<- error, sem chan struct{}) {
 	defer wg.Done()
 	sem <- struct{}{}
 	defer func() { <-sem }()
 
 	// Process the blob file for extraction
-	if err := c.processBlob(blobPath, outputDir); err != nil {
+	if err := c.processBlob(blobPath, outputDir, layer); err != nil {
 		errors <- err
+		return
 	}
+
+	event.Time = time.Now()
+	c.Progress.Report(event)
 }
 
 // Processes the blob file for extraction.
 // It checks for file existence, size, and identifies if it's a tar.gz blob.
-func (c *Controller) processBlob(blobPath, outputDir string) error {
+// Blobs that are not archives are written to the output directory under the title of the layer.
+func (c *Controller) processBlob(blobPath, outputDir string, layer ocispec.Descriptor) error {
 	fileInfo, err := os.Stat(blobPath)
 	if err != nil {
 		return fmt.Errorf("failed to stat blob %s: %w", blobPath, err)
@@ -109,10 +226,44 @@ func (c *Controller) processBlob(blobPath, outputDir string) error {
 	}
 	defer file.Close()
 
-	if isTarGzBlob(blobPath, file) {
+	if layer.Annotations[AnnotationUnpack] == "true" || isTarGzBlob(blobPath, file) {
 		return c.extractBlob(blobPath, file, outputDir)
 	}
 
+	if title := layer.Annotations[ocispec.AnnotationTitle]; title != "" {
+		return c.writeBlobFile(file, outputDir, title)
+	}
+
+	return nil
+}
+
+// Writes a blob that is not an archive to the output directory under the given title.
+// Existing files are kept, like for the entries of extracted archives.
+func (c *Controller) writeBlobFile(file *os.File, outputDir, title string) error {
+	destPath, err := safeJoin(outputDir, title)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(destPath); err == nil {
+		return nil
+	}
+	outPath, err := resolveInside(outputDir, destPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+	}
+
+	outFile, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", destPath, err)
+	}
+	defer outFile.Close()
+
+	if _, err := io.Copy(outFile, file); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", destPath, err)
+	}
 	return nil
 }
 
@@ -128,24 +279,11 @@ func isTarGzBlob(blobPath string, file *os.File) bool {
 }
 
 // Extracts a tar.gz blob to the specified output directory.
-// It handles timeouts during extraction.
+// The blob is read from the local store, so the extraction is not bounded by a deadline: a large
+// archive takes as long as the disk needs, and a corrupted one fails on its first invalid entry.
 func (c *Controller) extractBlob(blobPath string, file *os.File, outputDir string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), extractTimeout)
-	defer cancel()
-
-	extractErr := make(chan error, 1)
-	go func() {
-		extractErr <- c.extractTarGz(file, outputDir)
-	}()
-
-	select {
-	case err := <-extractErr:
-		if err != nil {
-			return fmt.Errorf("failed to extract tar.gz blob %s: %w", blobPath, err)
-		}
-	case <-ctx.Done():
-		return fmt.Errorf("timeout while extracting blob %s", blobPath)
+	if err := c.extractTarGz(file, outputDir); err != nil {
+		return fmt.Errorf("failed to extract tar.gz blob %s: %w", blobPath, err)
 	}
-
 	return nil
 }
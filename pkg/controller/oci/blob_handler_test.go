@@ -5,9 +5,14 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // Create a tar.gz file for testing
@@ -41,6 +46,37 @@ func createTarGzFile(t *testing.T, dest string, content map[string]string) {
 	}
 }
 
+// Create a tar.gz stream of the given entries, regular files hold their name
+func createTarGzEntries(t *testing.T, entries []tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, header := range entries {
+		header := header
+		header.Mode = 0600
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+		if err := tarWriter.WriteHeader(&header); err != nil {
+			t.Fatalf("failed to write tar header for %s: %v", header.Name, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tarWriter.Write([]byte(header.Name)); err != nil {
+				t.Fatalf("failed to write data for %s: %v", header.Name, err)
+			}
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("failed to close tar writer: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+	return &buf
+}
+
 // Test extractTarGz method
 func TestExtractTarGz(t *testing.T) {
 	dest := t.TempDir() // Temporary directory for extraction
@@ -79,3 +115,136 @@ func TestExtractTarGz(t *testing.T) {
 		}
 	}
 }
+
+// Test that extractTarGz rejects entries escaping the destination directory
+func TestExtractTarGzRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "output")
+	tarGzFile := filepath.Join(dir, "evil.tar.gz")
+
+	createTarGzFile(t, tarGzFile, map[string]string{
+		"../evil.txt": "escaped",
+	})
+
+	file, err := os.Open(tarGzFile)
+	if err != nil {
+		t.Fatalf("failed to open tar.gz file: %v", err)
+	}
+	defer file.Close()
+
+	if err := (&Controller{}).extractTarGz(file, dest); err == nil {
+		t.Fatalf("expected an error for an entry outside of the destination")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written outside of the destination, got %v", err)
+	}
+}
+
+// Test that extractTarGz rejects entries escaping the destination through symlinks created by previous entries
+func TestExtractTarGzRejectsChainedSymlinks(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []tar.Header
+	}{
+		{
+			name: "link inside a link to the destination",
+			entries: []tar.Header{
+				{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				{Name: "b/evil.txt", Typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name: "link through a link to the destination",
+			entries: []tar.Header{
+				{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/.."},
+				{Name: "b/evil.txt", Typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name: "link resolved by a later link",
+			entries: []tar.Header{
+				{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/.."},
+				{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+				{Name: "b/evil.txt", Typeflag: tar.TypeReg},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "output")
+
+			err := (&Controller{}).extractTarGz(createTarGzEntries(t, tc.entries), dest)
+			if _, statErr := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(statErr) {
+				t.Fatalf("expected no file to be written outside of the destination, got %v", statErr)
+			}
+			if err == nil {
+				t.Errorf("expected an error for an entry outside of the destination")
+			}
+		})
+	}
+}
+
+// Test that extractTarGz creates symlinks staying inside the destination, and writes through them
+func TestExtractTarGzSymlinks(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "output")
+
+	err := (&Controller{}).extractTarGz(createTarGzEntries(t, []tar.Header{
+		{Name: "logs/", Typeflag: tar.TypeDir},
+		{Name: "latest", Typeflag: tar.TypeSymlink, Linkname: "logs"},
+		{Name: "logs/current", Typeflag: tar.TypeSymlink, Linkname: "../latest/run.log"},
+		{Name: "latest/run.log", Typeflag: tar.TypeReg},
+	}), dest)
+	if err != nil {
+		t.Fatalf("failed to extract tar.gz: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dest, "logs", "current"))
+	if err != nil {
+		t.Fatalf("failed to read through the symlink: %v", err)
+	}
+	if string(content) != "latest/run.log" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+// Test that processBlobs returns the errors of the layers that failed to be extracted
+func TestProcessBlobsReportsFailedLayers(t *testing.T) {
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	valid := filepath.Join(t.TempDir(), "valid.tar.gz")
+	createTarGzFile(t, valid, map[string]string{"file1.txt": "This is the content of file1."})
+	validBlob, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatalf("failed to read tar.gz file: %v", err)
+	}
+	// A gzip header followed by garbage is detected as an archive but cannot be extracted
+	corruptBlob := []byte{0x1F, 0x8B, 0x08, 0x00, 'n', 'o', 't', ' ', 'g', 'z', 'i', 'p'}
+
+	var manifest ocispec.Manifest
+	for _, blob := range [][]byte{validBlob, corruptBlob} {
+		layer := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+		if err := os.MkdirAll(filepath.Dir(controller.blobPath(layer)), 0755); err != nil {
+			t.Fatalf("failed to create blob directory: %v", err)
+		}
+		if err := os.WriteFile(controller.blobPath(layer), blob, 0644); err != nil {
+			t.Fatalf("failed to write blob: %v", err)
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+	}
+
+	dest := t.TempDir()
+	err = controller.processBlobs(slog.Default(), "repo", "tag", manifest, dest)
+	if err == nil || !strings.Contains(err.Error(), "failed to extract 1 of 2 layers") {
+		t.Fatalf("expected the corrupted layer to be reported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "file1.txt")); err != nil {
+		t.Errorf("expected the valid layer to be extracted: %v", err)
+	}
+}
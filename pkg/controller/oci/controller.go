@@ -4,13 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/flacatus/oras-puller/pkg/progress"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/oci"
 )
 
+// defaultRegistry is the registry host of the repositories processed by the controller.
+const defaultRegistry = "quay.io"
+
 // Controller orchestrates operations on OCI repositories.
 // It holds the configuration for output and blob directories.
 type Controller struct {
@@ -25,6 +35,30 @@ type Controller struct {
 
 	// Store is the OCI store instance.
 	Store *oci.Store
+
+	// Progress receives the download and extraction events of every processed tag.
+	// It defaults to a reporter that discards all events.
+	Progress progress.Reporter
+
+	// Logger is the structured logger used for every record emitted by the controller.
+	// Records are enriched with repo, tag and digest attributes. It defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Since restricts the processed repository tags to those modified within this duration.
+	// A zero value processes every tag.
+	Since time.Duration
+
+	// MaxTotalSize is the budget in bytes for the compressed size of a run.
+	// Runs whose plan exceeds it are aborted before anything is downloaded. Zero disables the budget.
+	MaxTotalSize int64
+
+	// Registry is the host of the processed repositories. It defaults to quay.io,
+	// the only registry whose tags can be listed.
+	Registry string
+
+	// Source, when set, is read instead of the registry, such as an OCI image layout opened with
+	// OpenLocalSource. Tags are then resolved in the source and the repo name only names the output.
+	Source oras.ReadOnlyTarget
 }
 
 // NewController initializes a new Controller instance with the specified output and OCI store path.
@@ -39,20 +73,38 @@ func NewController(outputDir string, OCIStorePath string) (*Controller, error) {
 		BlobDir:      OCIStorePath + "/blobs/sha256/",
 		OCIStorePath: OCIStorePath,
 		Store:        store,
+		Progress:     progress.Nop(),
+		Logger:       slog.Default(),
+		Registry:     defaultRegistry,
 	}, nil
 }
 
+// DefaultCacheDir returns the directory of the OCI store shared by the commands when no cache
+// directory is configured: $HOME/.config/konflux-oci-artifacts/cache.
+func DefaultCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".config", "konflux-oci-artifacts", "cache"), nil
+}
+
 // FetchOCIContainerAnnotations fetches the OCI container annotations for a given repository and tag.
 // It retrieves the descriptor content by copying the tag manifest to the OCI store and unmarshaling it into a Descriptor struct.
+// Only the manifest is copied, the config and layers are left in the repository.
 func (c *Controller) FetchOCIContainerAnnotations(repo, tag string) (*v1.Descriptor, error) {
 	ctx := context.Background()
 
-	repoRemote, err := c.setupRemoteRepository(repo)
+	repoRemote, err := c.setupSource(repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up remote repository for %s: %w", repo, err)
 	}
 
-	if err := c.copyTagManifest(ctx, repoRemote, tag, c.Store); err != nil {
+	opts := oras.DefaultCopyOptions
+	opts.FindSuccessors = func(context.Context, content.Fetcher, ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		return nil, nil
+	}
+	if err := c.copyTagManifest(ctx, repoRemote, tag, c.Store, opts); err != nil {
 		return nil, fmt.Errorf("failed to copy manifest for tag %s: %w", tag, err)
 	}
 
@@ -70,9 +122,23 @@ func (c *Controller) FetchOCIContainerAnnotations(repo, tag string) (*v1.Descrip
 }
 
 // ProcessRepositories processes multiple repositories concurrently.
-// It fetches and processes tags for each repository, limiting concurrency to avoid overwhelming system resources.
-// Returns a slice of errors encountered during the processing of repositories.
+// It first plans every tag to download and verifies that the plan fits in the size budget and the
+// available disk space, then processes the tags of each repository, limiting concurrency to avoid
+// overwhelming system resources.
+// Returns a slice of errors encountered during the planning and processing of repositories.
 func (c *Controller) ProcessRepositories(repositories []string) []error {
+	plan, errors := c.PlanRepositories(repositories)
+	if err := c.CheckPlan(plan); err != nil {
+		return append(errors, err)
+	}
+	c.Logger.Info("Planned download", "tags", len(plan.Tags), "compressedSize", plan.CompressedSize,
+		"downloadSize", plan.DownloadSize, "estimatedExtractedSize", plan.ExtractedSize)
+
+	tagsByRepo := make(map[string][]TagPlan)
+	for _, tagPlan := range plan.Tags {
+		tagsByRepo[tagPlan.Repo] = append(tagsByRepo[tagPlan.Repo], tagPlan)
+	}
+
 	var wg sync.WaitGroup
 	errorsChan := make(chan error, len(repositories))
 
@@ -88,7 +154,7 @@ func (c *Controller) ProcessRepositories(repositories []string) []error {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			if err := c.processRepository(repo); err != nil {
+			if err := c.processRepository(repo, tagsByRepo[repo]); err != nil {
 				errorsChan <- fmt.Errorf("repository %s: %w", repo, err)
 			}
 		}(repo)
@@ -97,7 +163,6 @@ func (c *Controller) ProcessRepositories(repositories []string) []error {
 	wg.Wait()
 	close(errorsChan)
 
-	var errors []error
 	for err := range errorsChan {
 		errors = append(errors, err)
 	}
@@ -105,19 +170,15 @@ func (c *Controller) ProcessRepositories(repositories []string) []error {
 	return errors
 }
 
-// processRepository fetches and processes tags for a specific repository.
-// It returns an error if any issues occur while fetching or processing tags.
-func (c *Controller) processRepository(repo string) error {
-	// Fetch tags for the specified repository.
-	tags, err := c.FetchTags(repo)
-	if err != nil {
-		return fmt.Errorf("failed to fetch tags for repository %s: %w", repo, err)
-	}
+// processRepository processes the planned tags of a specific repository.
+// It returns an error if any issues occur while processing tags.
+func (c *Controller) processRepository(repo string, tags []TagPlan) error {
+	c.Logger.Info("Processing repository tags", "repo", repo, "tags", len(tags))
 
 	// Process each tag within the repository.
-	for _, tagInfo := range tags {
-		if err := c.ProcessTag(repo, tagInfo.Name, tagInfo.LastModified); err != nil {
-			return fmt.Errorf("failed to process tag %s in repository %s: %w", tagInfo.Name, repo, err)
+	for _, tagPlan := range tags {
+		if err := c.ProcessTag(repo, tagPlan.Tag, tagPlan.LastModified); err != nil {
+			return fmt.Errorf("failed to process tag %s in repository %s: %w", tagPlan.Tag, repo, err)
 		}
 	}
 
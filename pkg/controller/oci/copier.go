@@ -0,0 +1,105 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+)
+
+// CopyOptions configures the copy of artifacts between repositories and layouts.
+type CopyOptions struct {
+	// Referrers also copies the artifacts attached to the copied manifests, such as signatures
+	// and test reports, along with the artifacts attached to those.
+	Referrers bool
+
+	// MountFrom is the repository the blobs are mounted from when the destination is a repository
+	// of the same registry. Empty disables cross-repository mounts.
+	MountFrom string
+}
+
+// CopyStats counts the manifests and blobs handled by copies. It is safe for concurrent use.
+type CopyStats struct {
+	// Copied is the number of manifests and blobs uploaded to the destination.
+	Copied atomic.Int64
+
+	// Bytes is the size of the content uploaded to the destination.
+	Bytes atomic.Int64
+
+	// Mounted is the number of blobs mounted from the source repository instead of uploaded.
+	Mounted atomic.Int64
+
+	// Skipped is the number of manifests and blobs that already existed in the destination,
+	// including the content they reference.
+	Skipped atomic.Int64
+}
+
+// CopyArtifact copies the artifact referenced by srcRef, with every blob it references, to dst and
+// tags it with dstRef. Digests are preserved, and content that already exists in the destination
+// is not uploaded again. The handled content is counted in stats. Large blobs may take long to
+// copy, so only a blob transfer idle for blobIdleTimeout is aborted, besides ctx.
+func (c *Controller) CopyArtifact(ctx context.Context, src oras.ReadOnlyTarget, srcRef string, dst oras.Target, dstRef string, opts CopyOptions, stats *CopyStats) (ocispec.Descriptor, error) {
+	src = newIdleSource(src, blobIdleTimeout)
+
+	graphOpts := oras.DefaultCopyGraphOptions
+	graphOpts.PostCopy = func(_ context.Context, desc ocispec.Descriptor) error {
+		stats.Copied.Add(1)
+		stats.Bytes.Add(desc.Size)
+		return nil
+	}
+	graphOpts.OnCopySkipped = func(_ context.Context, desc ocispec.Descriptor) error {
+		stats.Skipped.Add(1)
+		return nil
+	}
+	graphOpts.OnMounted = func(_ context.Context, desc ocispec.Descriptor) error {
+		stats.Mounted.Add(1)
+		return nil
+	}
+	if opts.MountFrom != "" {
+		graphOpts.MountFrom = func(context.Context, ocispec.Descriptor) ([]string, error) {
+			return []string{opts.MountFrom}, nil
+		}
+	}
+
+	if !opts.Referrers {
+		root, err := oras.Copy(ctx, src, srcRef, dst, dstRef, oras.CopyOptions{CopyGraphOptions: graphOpts})
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("failed to copy %s: %w", srcRef, err)
+		}
+		return root, nil
+	}
+
+	graphSrc, ok := src.(oras.ReadOnlyGraphTarget)
+	if !ok {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to copy %s: the source cannot list referrers", srcRef)
+	}
+	extendedOpts := oras.DefaultExtendedCopyOptions
+	extendedOpts.CopyGraphOptions = graphOpts
+	root, err := oras.ExtendedCopy(ctx, graphSrc, srcRef, dst, dstRef, extendedOpts)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to copy %s with its referrers: %w", srcRef, err)
+	}
+	return root, nil
+}
+
+// MirrorTags copies the tags of a repository to dst under the same names, one tag at a time, like
+// CopyArtifact with no deadline besides ctx. Returns the errors of the tags that could not be copied.
+func (c *Controller) MirrorTags(ctx context.Context, repo string, tags []TagInfo, dst oras.Target, opts CopyOptions, stats *CopyStats) []error {
+	src, err := c.setupSource(repo)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errors []error
+	for _, tagInfo := range tags {
+		root, err := c.CopyArtifact(ctx, src, tagInfo.Name, dst, tagInfo.Name, opts, stats)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("repository %s: %w", repo, err))
+			continue
+		}
+		c.Logger.Info("Mirrored tag", "repo", repo, "tag", tagInfo.Name, "digest", root.Digest.String())
+	}
+	return errors
+}
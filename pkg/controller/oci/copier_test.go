@@ -0,0 +1,84 @@
+package oci
+
+import (
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/content/oci"
+)
+
+// TestCopyArtifact verifies that referrers are copied on request and that existing content is skipped.
+func TestCopyArtifact(t *testing.T) {
+	ctx := context.Background()
+	src, err := oci.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	layer, err := oras.PushBytes(ctx, src, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+	manifest, err := oras.PackManifest(ctx, src, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layer},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	if err := src.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatalf("failed to tag manifest: %v", err)
+	}
+	referrer, err := oras.PackManifest(ctx, src, oras.PackManifestVersion1_1, "application/vnd.konflux.report", oras.PackManifestOptions{
+		Subject: &manifest,
+	})
+	if err != nil {
+		t.Fatalf("failed to pack referrer: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	dst := memory.New()
+	stats := &CopyStats{}
+	root, err := controller.CopyArtifact(ctx, src, "v1", dst, "promoted", CopyOptions{}, stats)
+	if err != nil {
+		t.Fatalf("failed to copy artifact: %v", err)
+	}
+	if root.Digest != manifest.Digest {
+		t.Errorf("expected digest %s to be preserved, got %s", manifest.Digest, root.Digest)
+	}
+	if desc, err := dst.Resolve(ctx, "promoted"); err != nil || desc.Digest != manifest.Digest {
+		t.Errorf("expected the promoted tag to reference %s: %v", manifest.Digest, err)
+	}
+	if exists, _ := dst.Exists(ctx, referrer); exists {
+		t.Error("expected the referrer not to be copied without the option")
+	}
+	if stats.Copied.Load() != 3 || stats.Bytes.Load() != layer.Size+manifest.Size+2 {
+		t.Errorf("unexpected stats: copied %d, %d bytes", stats.Copied.Load(), stats.Bytes.Load())
+	}
+
+	stats = &CopyStats{}
+	if _, err := controller.CopyArtifact(ctx, src, "v1", dst, "promoted", CopyOptions{Referrers: true}, stats); err != nil {
+		t.Fatalf("failed to copy artifact with referrers: %v", err)
+	}
+	if exists, _ := dst.Exists(ctx, referrer); !exists {
+		t.Error("expected the referrer to be copied")
+	}
+	if stats.Copied.Load() != 1 || stats.Skipped.Load() == 0 {
+		t.Errorf("expected only the referrer to be copied, got copied %d, skipped %d", stats.Copied.Load(), stats.Skipped.Load())
+	}
+
+	// Mirroring keeps the tag names
+	controller.Source = src
+	mirror := memory.New()
+	if errors := controller.MirrorTags(ctx, "org/repo", []TagInfo{{Name: "v1"}, {Name: "missing"}}, mirror, CopyOptions{}, &CopyStats{}); len(errors) != 1 {
+		t.Errorf("expected one error for the missing tag, got %v", errors)
+	}
+	if _, err := mirror.Resolve(ctx, "v1"); err != nil {
+		t.Errorf("expected the tag v1 to be mirrored: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// CredentialOptions configures the credentials the remote repositories are authenticated with.
+type CredentialOptions struct {
+	// RegistryConfig is the path of the registry configuration file holding the credentials, such as
+	// a podman auth.json or a mounted Kubernetes .dockerconfigjson secret. It defaults to the Docker
+	// configuration: $DOCKER_CONFIG/config.json or $HOME/.docker/config.json.
+	RegistryConfig string
+
+	// Username and Password, when set, are used for Registry instead of its stored credentials.
+	Username string
+	Password string
+
+	// Registry is the host Username and Password are sent to, defaulting to quay.io. The other
+	// registries, such as the destination of a copy, keep their stored credentials.
+	Registry string
+}
+
+// credentialOptions are the options of every remote repository created by the package.
+var credentialOptions CredentialOptions
+
+// SetCredentialOptions sets the credentials of the remote repositories created afterwards.
+func SetCredentialOptions(opts CredentialOptions) {
+	credentialOptions = opts
+}
+
+// GetCredentialOptions returns the credential options set with SetCredentialOptions.
+func GetCredentialOptions() CredentialOptions {
+	return credentialOptions
+}
+
+// NewCredentialStore opens the credential store of the registry configuration file. Credentials
+// are read from and saved to the credential helpers configured in the file, or the platform
+// default helper when the file configures none, and in the file itself when no helper is available.
+func NewCredentialStore() (*credentials.DynamicStore, error) {
+	opts := credentials.StoreOptions{AllowPlaintextPut: true, DetectDefaultNativeStore: true}
+	if credentialOptions.RegistryConfig != "" {
+		store, err := credentials.NewStore(credentialOptions.RegistryConfig, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load registry configuration %s: %w", credentialOptions.RegistryConfig, err)
+		}
+		return store, nil
+	}
+
+	store, err := credentials.NewStoreFromDocker(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Docker configuration: %w", err)
+	}
+	return store, nil
+}
+
+// credential returns the function resolving the credential of a registry: the configured username
+// and password for their registry, or the credential of the registry in the credential store. When
+// the store cannot be loaded or read, the registries are accessed anonymously, which is enough for
+// public repositories. The source of the credentials is recorded to explain the authentication failures.
+func credential() auth.CredentialFunc {
+	storeCredential := credentialFromStore()
+	if credentialOptions.Username == "" {
+		return storeCredential
+	}
+
+	registry := credentialOptions.Registry
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	credentialSource.setRegistrySource(registry, "the --username flag")
+	cred := auth.Credential{Username: credentialOptions.Username, Password: credentialOptions.Password}
+	return func(ctx context.Context, hostport string) (auth.Credential, error) {
+		// The credential must not leak to the other registries the command talks to
+		if hostport != registry {
+			return storeCredential(ctx, hostport)
+		}
+		credentialSource.record(hostport, cred)
+		return cred, nil
+	}
+}
+
+// credentialFromStore returns the function resolving the credential of a registry from the
+// credential store, falling back to anonymous access.
+func credentialFromStore() auth.CredentialFunc {
+	store, err := NewCredentialStore()
+	if err != nil {
+		slog.Warn("Failed to load the registry credentials, falling back to anonymous access", "error", err)
+		credentialSource.setSource(fmt.Sprintf("the registry configuration, which could not be loaded (%v)", err))
+		return func(_ context.Context, hostport string) (auth.Credential, error) {
+			credentialSource.record(hostport, auth.EmptyCredential)
+			return auth.EmptyCredential, nil
+		}
+	}
+
+	credentialSource.setSource("the registry configuration " + store.ConfigPath())
+	storeCredential := credentials.Credential(store)
+	return func(ctx context.Context, hostport string) (auth.Credential, error) {
+		cred, err := storeCredential(ctx, hostport)
+		if err != nil {
+			// A missing or failing credential helper must not prevent pulling public content
+			slog.Warn("Failed to read the registry credentials, falling back to anonymous access", "registry", hostport, "error", err)
+			cred = auth.EmptyCredential
+		}
+		credentialSource.record(hostport, cred)
+		return cred, nil
+	}
+}
+
+// Login verifies the credential against the registry and saves it to the credential store.
+// Returns the path of the registry configuration file of the store.
+func Login(ctx context.Context, registry string, cred auth.Credential) (string, error) {
+	store, err := NewCredentialStore()
+	if err != nil {
+		return "", err
+	}
+
+	reg, err := remote.NewRegistry(registry)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry %s: %w", registry, err)
+	}
+	reg.Client = &auth.Client{Client: retry.DefaultClient, Cache: auth.NewCache()}
+
+	if err := credentials.Login(ctx, store, reg, cred); err != nil {
+		return "", err
+	}
+	return store.ConfigPath(), nil
+}
+
+// Logout removes the credential of the registry from the credential store.
+// Returns the path of the registry configuration file of the store.
+func Logout(ctx context.Context, registry string) (string, error) {
+	store, err := NewCredentialStore()
+	if err != nil {
+		return "", err
+	}
+	if err := credentials.Logout(ctx, store, registry); err != nil {
+		return "", err
+	}
+	return store.ConfigPath(), nil
+}
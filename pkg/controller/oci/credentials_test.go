@@ -0,0 +1,129 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// writeRegistryConfig writes an auth.json holding a credential of example.com and returns its path.
+func writeRegistryConfig(t *testing.T) string {
+	t.Helper()
+	encoded := base64.StdEncoding.EncodeToString([]byte("robot:secret"))
+	file := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(file, []byte(`{"auths":{"example.com":{"auth":"`+encoded+`"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// setCredentialOptions sets the credential options for the duration of the test.
+func setCredentialOptions(t *testing.T, opts CredentialOptions) {
+	t.Helper()
+	SetCredentialOptions(opts)
+	t.Cleanup(func() { SetCredentialOptions(CredentialOptions{}) })
+}
+
+// TestCredentialFromRegistryConfig verifies that credentials are read from an alternate registry configuration.
+func TestCredentialFromRegistryConfig(t *testing.T) {
+	setCredentialOptions(t, CredentialOptions{RegistryConfig: writeRegistryConfig(t)})
+
+	cred, err := credential()(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("failed to get credential: %v", err)
+	}
+	if cred.Username != "robot" || cred.Password != "secret" {
+		t.Errorf("unexpected credential %+v", cred)
+	}
+}
+
+// TestCredentialFromUsername verifies that an explicit username and password take precedence for
+// their registry only, the other registries keep their stored credentials.
+func TestCredentialFromUsername(t *testing.T) {
+	testCases := []struct {
+		name             string
+		registry         string
+		host             string
+		expectedUsername string
+	}{
+		{name: "default registry", host: "quay.io", expectedUsername: "user"},
+		{name: "other registry", host: "example.com", expectedUsername: "robot"},
+		{name: "configured registry", registry: "example.com", host: "example.com", expectedUsername: "user"},
+		{name: "other than configured registry", registry: "localhost:5000", host: "quay.io", expectedUsername: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setCredentialOptions(t, CredentialOptions{RegistryConfig: writeRegistryConfig(t), Username: "user", Password: "token", Registry: tc.registry})
+
+			cred, err := credential()(context.Background(), tc.host)
+			if err != nil {
+				t.Fatalf("failed to get credential: %v", err)
+			}
+			if cred.Username != tc.expectedUsername {
+				t.Errorf("expected username %q, got %+v", tc.expectedUsername, cred)
+			}
+			if tc.expectedUsername == "user" && cred.Password != "token" {
+				t.Errorf("unexpected credential %+v", cred)
+			}
+		})
+	}
+}
+
+// TestLogout verifies that the credential of the registry is removed from the registry configuration.
+func TestLogout(t *testing.T) {
+	file := writeRegistryConfig(t)
+	setCredentialOptions(t, CredentialOptions{RegistryConfig: file})
+
+	configPath, err := Logout(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if configPath != file {
+		t.Errorf("expected configuration %s, got %s", file, configPath)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "example.com") {
+		t.Errorf("expected the credential to be removed, got %s", content)
+	}
+}
+
+// TestCredentialFallsBackToAnonymous verifies that an unreadable registry configuration results in anonymous access.
+func TestCredentialFallsBackToAnonymous(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(file, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setCredentialOptions(t, CredentialOptions{RegistryConfig: file})
+
+	cred, err := credential()(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("expected anonymous access, got %v", err)
+	}
+	if cred != auth.EmptyCredential {
+		t.Errorf("expected an empty credential, got %+v", cred)
+	}
+}
+
+// TestCredentialSourcePerRegistry verifies that authentication failures name the credential source of each registry.
+func TestCredentialSourcePerRegistry(t *testing.T) {
+	file := writeRegistryConfig(t)
+	setCredentialOptions(t, CredentialOptions{RegistryConfig: file, Username: "user", Password: "token"})
+	credential()
+
+	if source, _ := credentialSource.lookup("quay.io"); source != "the --username flag" {
+		t.Errorf("unexpected source for quay.io: %s", source)
+	}
+	if source, _ := credentialSource.lookup("example.com"); source != "the registry configuration "+file {
+		t.Errorf("unexpected source for example.com: %s", source)
+	}
+}
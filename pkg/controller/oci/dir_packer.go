@@ -0,0 +1,304 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Annotations understood by ORAS compatible clients for layers holding a packed directory.
+const (
+	// AnnotationUnpack marks a layer as a directory archive that must be extracted on pull.
+	AnnotationUnpack = "io.deis.oras.content.unpack"
+
+	// AnnotationDigest records the digest of the uncompressed tar archive of a directory layer.
+	AnnotationDigest = "io.deis.oras.content.digest"
+)
+
+// IgnoreFileName is the name of the file listing the patterns excluded when packing a directory.
+const IgnoreFileName = ".ociignore"
+
+// PathFilter selects the entries of a directory that are packed into a layer.
+// Patterns use path.Match syntax and are matched against the slash separated path relative to the
+// packed directory. Patterns without a slash are also matched against the base name of every entry.
+type PathFilter struct {
+	// Include restricts the packed files to those matching at least one pattern. Empty includes every file.
+	Include []string
+
+	// Exclude skips the files and directories matching any pattern.
+	Exclude []string
+}
+
+// ignoreRule is a single exclude pattern. Negated rules re-include entries excluded by earlier rules.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// packEntry is a file system entry selected for the archive.
+type packEntry struct {
+	rel  string
+	path string
+	info fs.FileInfo
+}
+
+// PackDirectory writes a deterministic tar+gzip archive of dir to w and returns the digest of the
+// uncompressed tar. Entries are sorted, prefixed with the base name of dir, and stripped of
+// timestamps and ownership, so that packing the same tree twice produces the same layer digest.
+// Patterns of the .ociignore file at the root of dir are applied in addition to the filter.
+func PackDirectory(dir string, w io.Writer, filter PathFilter) (digest.Digest, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", dir, err)
+	}
+
+	rules, err := loadIgnoreRules(absDir, filter.Exclude)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := collectEntries(absDir, rules, filter.Include)
+	if err != nil {
+		return "", err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	digester := digest.Canonical.Digester()
+	tarWriter := tar.NewWriter(io.MultiWriter(gzipWriter, digester.Hash()))
+
+	root := filepath.Base(absDir)
+	if err := writeTarEntry(tarWriter, packEntry{rel: "", path: absDir}, root); err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if err := writeTarEntry(tarWriter, entry, path.Join(root, entry.rel)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize tar archive of %s: %w", dir, err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize gzip stream of %s: %w", dir, err)
+	}
+
+	return digester.Digest(), nil
+}
+
+// collectEntries walks dir and returns the sorted entries kept by the ignore rules and include patterns.
+// When include patterns are given, only the directories leading to an included file are kept.
+func collectEntries(dir string, rules []ignoreRule, include []string) ([]packEntry, error) {
+	var entries []packEntry
+	keepDirs := make(map[string]bool)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if isIgnored(rules, rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if len(include) == 0 {
+				keepDirs[rel] = true
+			}
+		case info.Mode().IsRegular() || info.Mode()&fs.ModeSymlink != 0:
+			if len(include) > 0 && !matchesAny(include, rel) {
+				return nil
+			}
+			for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+				keepDirs[parent] = true
+			}
+		default:
+			// Sockets, devices and pipes cannot be recreated on download.
+			return nil
+		}
+
+		entries = append(entries, packEntry{rel: rel, path: p, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %w", dir, err)
+	}
+
+	var kept []packEntry
+	for _, entry := range entries {
+		if entry.info.IsDir() && !keepDirs[entry.rel] {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	sort.Slice(kept, func(i, j int) bool { return kept[i].rel < kept[j].rel })
+	return kept, nil
+}
+
+// writeTarEntry writes the header and, for regular files, the content of an entry with normalized metadata.
+func writeTarEntry(tw *tar.Writer, entry packEntry, name string) error {
+	info := entry.info
+	if info == nil {
+		var err error
+		if info, err = os.Lstat(entry.path); err != nil {
+			return fmt.Errorf("failed to stat %s: %w", entry.path, err)
+		}
+	}
+
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(entry.path); err != nil {
+			return fmt.Errorf("failed to read symlink %s: %w", entry.path, err)
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("failed to create tar header for %s: %w", entry.path, err)
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	header.Mode = int64(info.Mode().Perm())
+	header.ModTime = time.Unix(0, 0)
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	header.Format = tar.FormatPAX
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", entry.path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", entry.path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write %s to the archive: %w", entry.path, err)
+	}
+	return nil
+}
+
+// loadIgnoreRules combines the exclude patterns with the rules of the .ociignore file of dir, if any.
+func loadIgnoreRules(dir string, exclude []string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, pattern := range exclude {
+		rules = append(rules, parseIgnoreRule(pattern))
+	}
+
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", IgnoreFileName, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, parseIgnoreRule(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+
+	return rules, nil
+}
+
+// parseIgnoreRule parses a pattern in the .ociignore syntax: a leading "!" negates the rule,
+// a trailing "/" restricts it to directories and a leading "/" anchors it to the root.
+func parseIgnoreRule(pattern string) ignoreRule {
+	rule := ignoreRule{}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	rule.pattern = strings.TrimPrefix(pattern, "/")
+	return rule
+}
+
+// isIgnored evaluates the rules in order for an entry; the last matching rule wins.
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchPattern(rule.pattern, rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchesAny reports whether rel matches at least one of the patterns.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a pattern against the relative path, or against the base name
+// when the pattern does not contain a slash.
+func matchPattern(pattern, rel string) bool {
+	if ok, _ := path.Match(pattern, rel); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return false
+}
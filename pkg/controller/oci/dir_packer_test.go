@@ -0,0 +1,115 @@
+package oci
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createTree writes the given files below root, creating parent directories as needed.
+func createTree(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// TestPackDirectoryRoundTrip verifies that a packed directory is extracted to the same tree,
+// without the ignored entries, and that packing is deterministic.
+func TestPackDirectoryRoundTrip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "artifacts")
+	createTree(t, src, map[string]string{
+		"junit.xml":            "<testsuites/>",
+		"logs/pod-1.log":       "panic: runtime error",
+		"logs/debug.tmp":       "ignored by .ociignore",
+		"cache/blob":           "ignored by exclude filter",
+		IgnoreFileName:         "# temporary files\n*.tmp\n",
+		"reports/deep/out.txt": "nested",
+	})
+	if err := os.Symlink("junit.xml", filepath.Join(src, "latest.xml")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	filter := PathFilter{Exclude: []string{"cache/"}}
+
+	var first bytes.Buffer
+	firstDigest, err := PackDirectory(src, &first, filter)
+	if err != nil {
+		t.Fatalf("failed to pack directory: %v", err)
+	}
+
+	// Touch a file to make sure timestamps do not leak into the archive.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(src, "junit.xml"), later, later); err != nil {
+		t.Fatalf("failed to change timestamps: %v", err)
+	}
+
+	var second bytes.Buffer
+	secondDigest, err := PackDirectory(src, &second, filter)
+	if err != nil {
+		t.Fatalf("failed to pack directory again: %v", err)
+	}
+	if firstDigest != secondDigest || !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("expected packing to be deterministic, got digests %s and %s", firstDigest, secondDigest)
+	}
+
+	dest := t.TempDir()
+	if err := (&Controller{}).extractTarGz(&first, dest); err != nil {
+		t.Fatalf("failed to extract packed directory: %v", err)
+	}
+
+	for _, name := range []string{"junit.xml", "logs/pod-1.log", "reports/deep/out.txt", IgnoreFileName} {
+		want, _ := os.ReadFile(filepath.Join(src, name))
+		got, err := os.ReadFile(filepath.Join(dest, "artifacts", name))
+		if err != nil {
+			t.Errorf("expected %s to be extracted: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("content mismatch for %s", name)
+		}
+	}
+
+	for _, name := range []string{"logs/debug.tmp", "cache"} {
+		if _, err := os.Stat(filepath.Join(dest, "artifacts", name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be excluded, got %v", name, err)
+		}
+	}
+
+	if link, err := os.Readlink(filepath.Join(dest, "artifacts", "latest.xml")); err != nil || link != "junit.xml" {
+		t.Errorf("expected symlink latest.xml -> junit.xml, got %q (%v)", link, err)
+	}
+}
+
+// TestPackDirectoryInclude verifies that include patterns keep only matching files and their parents.
+func TestPackDirectoryInclude(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "run")
+	createTree(t, src, map[string]string{
+		"a/report.xml": "<xml/>",
+		"b/trace.json": "{}",
+	})
+
+	var archive bytes.Buffer
+	if _, err := PackDirectory(src, &archive, PathFilter{Include: []string{"*.xml"}}); err != nil {
+		t.Fatalf("failed to pack directory: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := (&Controller{}).extractTarGz(&archive, dest); err != nil {
+		t.Fatalf("failed to extract packed directory: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "run", "a", "report.xml")); err != nil {
+		t.Errorf("expected included file to be extracted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "run", "b")); !os.IsNotExist(err) {
+		t.Errorf("expected directory without included files to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package oci
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// statfs is not implemented on this platform, so the disk space check is skipped.
+func statfs(path string) (filesystem, error) {
+	return filesystem{}, fmt.Errorf("disk space check is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin
+
+package oci
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// statfs returns the device identifier and the space available to unprivileged users for path.
+func statfs(path string) (filesystem, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return filesystem{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return filesystem{}, fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+	}
+
+	return filesystem{
+		id:   uint64(stat.Dev),
+		free: uint64(fs.Bavail) * uint64(fs.Bsize),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// LabelExpiresAfter is the image config label Quay reads to expire a tag after the given period.
+const LabelExpiresAfter = "quay.expires-after"
+
+// expiresAfterPattern matches the periods understood by Quay, such as 12h, 7d or 2w.
+var expiresAfterPattern = regexp.MustCompile(`^([1-9][0-9]*)([smhdw])$`)
+
+// expiresAfterUnits maps the units of an expiration period to their duration.
+var expiresAfterUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseExpiresAfter parses an expiration period in the format of the quay.expires-after label.
+func ParseExpiresAfter(value string) (time.Duration, error) {
+	match := expiresAfterPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("invalid expiration period %q (expected a number followed by s, m, h, d or w, e.g., 7d)", value)
+	}
+	count, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiration period %q: %w", value, err)
+	}
+	return time.Duration(count) * expiresAfterUnits[match[2]], nil
+}
+
+// ExpirationConfig returns an image config blob carrying the quay.expires-after label.
+// Quay only reads labels from image configs, so artifacts pushed with the empty config never expire.
+func ExpirationConfig(expiresAfter string) ([]byte, error) {
+	if _, err := ParseExpiresAfter(expiresAfter); err != nil {
+		return nil, err
+	}
+
+	config := ocispec.Image{
+		Config: ocispec.ImageConfig{
+			Labels: map[string]string{LabelExpiresAfter: expiresAfter},
+		},
+		RootFS: ocispec.RootFS{Type: "layers", DiffIDs: []digest.Digest{}},
+	}
+	content, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal image config: %w", err)
+	}
+	return content, nil
+}
+
+// SetQuayTagExpiration sets the expiration of a tag through the Quay API of the registry host,
+// authenticating with an OAuth application token.
+func SetQuayTagExpiration(ctx context.Context, host, repo, tag string, expiration time.Time, token string) error {
+	return setTagExpiration(ctx, http.DefaultClient, "https://"+host+"/api/v1/repository/", repo, tag, expiration, token)
+}
+
+// setTagExpiration sends the tag update request to the given Quay API base URL.
+func setTagExpiration(ctx context.Context, client *http.Client, apiURL, repo, tag string, expiration time.Time, token string) error {
+	body, err := json.Marshal(map[string]int64{"expiration": expiration.Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tag expiration: %w", err)
+	}
+
+	url := fmt.Sprintf("%s%s/tag/%s", apiURL, repo, tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create tag expiration request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to set expiration of tag %s: %w", tag, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to set expiration of tag %s: %s", tag, resp.Status)
+	}
+	return nil
+}
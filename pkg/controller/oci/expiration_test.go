@@ -0,0 +1,71 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// TestParseExpiresAfter verifies the supported expiration periods.
+func TestParseExpiresAfter(t *testing.T) {
+	cases := map[string]time.Duration{
+		"12h": 12 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+	}
+	for value, expected := range cases {
+		got, err := ParseExpiresAfter(value)
+		if err != nil || got != expected {
+			t.Errorf("ParseExpiresAfter(%q) = %v, %v; expected %v", value, got, err, expected)
+		}
+	}
+
+	for _, value := range []string{"", "7", "0d", "7y", "-1d"} {
+		if _, err := ParseExpiresAfter(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+// TestExpirationConfig verifies that the generated config carries the Quay label.
+func TestExpirationConfig(t *testing.T) {
+	content, err := ExpirationConfig("7d")
+	if err != nil {
+		t.Fatalf("failed to generate config: %v", err)
+	}
+
+	var config ocispec.Image
+	if err := json.Unmarshal(content, &config); err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if config.Config.Labels[LabelExpiresAfter] != "7d" {
+		t.Errorf("expected label %s=7d, got %v", LabelExpiresAfter, config.Config.Labels)
+	}
+}
+
+// TestSetTagExpiration verifies the request sent to the Quay API.
+func TestSetTagExpiration(t *testing.T) {
+	expiration := time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]int64
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.Method != http.MethodPut || r.URL.Path != "/api/v1/repository/org/repo/tag/v1" ||
+			r.Header.Get("Authorization") != "Bearer secret" || body["expiration"] != expiration.Unix() {
+			t.Errorf("unexpected request %s %s %v", r.Method, r.URL.Path, body)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	err := setTagExpiration(context.Background(), server.Client(), server.URL+"/api/v1/repository/", "org/repo", "v1", expiration, "secret")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+)
+
+// idleSource wraps a source target so that the transfer of a fetched blob is aborted when no byte
+// is read for the idle timeout. Unlike a deadline, it does not limit the size of the blobs.
+type idleSource struct {
+	oras.ReadOnlyTarget
+
+	timeout time.Duration
+}
+
+// idleGraphSource is an idleSource of a target that can also list the referrers of its manifests.
+type idleGraphSource struct {
+	*idleSource
+
+	predecessors content.PredecessorFinder
+}
+
+// newIdleSource returns src with the transfer of its blobs aborted after timeout without reading any byte.
+func newIdleSource(src oras.ReadOnlyTarget, timeout time.Duration) oras.ReadOnlyTarget {
+	idle := &idleSource{ReadOnlyTarget: src, timeout: timeout}
+	if graph, ok := src.(oras.ReadOnlyGraphTarget); ok {
+		return &idleGraphSource{idleSource: idle, predecessors: graph}
+	}
+	return idle
+}
+
+// Predecessors returns the nodes directly pointing to the given node.
+func (s *idleGraphSource) Predecessors(ctx context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	return s.predecessors.Predecessors(ctx, node)
+}
+
+// Fetch fetches the content identified by the descriptor with a context cancelled once the stream
+// stays idle for the timeout of the source.
+func (s *idleSource) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(s.timeout, func() {
+		cancel(fmt.Errorf("no data received for %s while fetching blob %s", s.timeout, target.Digest))
+	})
+
+	rc, err := s.ReadOnlyTarget.Fetch(ctx, target)
+	if err != nil {
+		timer.Stop()
+		cancel(nil)
+		return nil, err
+	}
+	idle := &idleReadCloser{ReadCloser: rc, ctx: ctx, cancel: cancel, timer: timer, timeout: s.timeout}
+	// Remote blobs are seekable to resume interrupted downloads
+	if seeker, ok := rc.(io.Seeker); ok {
+		return &idleReadSeekCloser{idleReadCloser: idle, seeker: seeker}, nil
+	}
+	return idle, nil
+}
+
+// idleReadCloser restarts the idle timer of a fetched blob every time bytes are read.
+type idleReadCloser struct {
+	io.ReadCloser
+
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+// Read reads from the blob and reports the idle timeout as the cause of a cancelled read.
+func (r *idleReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	if err != nil && err != io.EOF && r.ctx.Err() != nil {
+		err = context.Cause(r.ctx)
+	}
+	return n, err
+}
+
+// Close closes the blob and stops its idle timer.
+func (r *idleReadCloser) Close() error {
+	r.timer.Stop()
+	r.cancel(nil)
+	return r.ReadCloser.Close()
+}
+
+// idleReadSeekCloser is an idleReadCloser of a seekable blob.
+type idleReadSeekCloser struct {
+	*idleReadCloser
+
+	seeker io.Seeker
+}
+
+// Seek sets the offset of the next read of the blob.
+func (r *idleReadSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
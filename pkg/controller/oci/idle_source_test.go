@@ -0,0 +1,129 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// newSlowBlobServer serves a blob in chunks of chunkSize bytes, waiting delay between them.
+// When stall is set, the server stops sending after the first chunk until the request is cancelled.
+func newSlowBlobServer(t *testing.T, blob []byte, chunkSize int, delay time.Duration, stall bool) *remote.Repository {
+	dgst := digest.FromBytes(blob)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/test/repo/blobs/"+dgst.String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(blob)))
+		for offset := 0; offset < len(blob); offset += chunkSize {
+			if offset > 0 {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(delay):
+				}
+				if stall {
+					<-r.Context().Done()
+					return
+				}
+			}
+			w.Write(blob[offset:min(offset+chunkSize, len(blob))])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := remote.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/test/repo")
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	repo.PlainHTTP = true
+	return repo
+}
+
+// TestIdleSourceCompletesSlowTransfer verifies that a transfer taking longer than the idle timeout
+// completes as long as bytes keep flowing.
+func TestIdleSourceCompletesSlowTransfer(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 100))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+	src := newIdleSource(newSlowBlobServer(t, blob, 100, 20*time.Millisecond, false), 100*time.Millisecond)
+
+	start := time.Now()
+	rc, err := src.Fetch(context.Background(), desc)
+	if err != nil {
+		t.Fatalf("failed to fetch blob: %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if string(data) != string(blob) {
+		t.Errorf("unexpected blob content")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the transfer to outlast the idle timeout, took %s", elapsed)
+	}
+}
+
+// TestIdleSourceAbortsStalledTransfer verifies that a transfer receiving no byte for the idle
+// timeout is aborted with an explicit error.
+func TestIdleSourceAbortsStalledTransfer(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 100))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+	src := newIdleSource(newSlowBlobServer(t, blob, 100, 10*time.Millisecond, true), 100*time.Millisecond)
+
+	rc, err := src.Fetch(context.Background(), desc)
+	if err != nil {
+		t.Fatalf("failed to fetch blob: %v", err)
+	}
+	defer rc.Close()
+
+	_, err = io.ReadAll(rc)
+	if err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Errorf("expected an idle timeout error, got %v", err)
+	}
+}
+
+// TestIdleSourceResumesDownload verifies that blobs of an idle source can still be resumed with a range request.
+func TestIdleSourceResumesDownload(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 1000))
+	desc := ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromBytes(blob), Size: int64(len(blob))}
+
+	var ranges []string
+	src := newIdleSource(newBlobServer(t, blob, &ranges), time.Minute)
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	partialPath := controller.partialBlobPath(desc)
+	if err := os.MkdirAll(filepath.Dir(partialPath), 0755); err != nil {
+		t.Fatalf("failed to create partial directory: %v", err)
+	}
+	if err := os.WriteFile(partialPath, blob[:5000], 0644); err != nil {
+		t.Fatalf("failed to write partial blob: %v", err)
+	}
+
+	if err := controller.fetchBlobResumable(context.Background(), src, desc, progress.Event{}); err != nil {
+		t.Fatalf("failed to fetch blob: %v", err)
+	}
+	expectedRange := fmt.Sprintf("bytes=5000-%d", len(blob)-1)
+	if len(ranges) != 2 || ranges[1] != expectedRange {
+		t.Errorf("expected a resumed request with range %q, got %v", expectedRange, ranges)
+	}
+}
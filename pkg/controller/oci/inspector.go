@@ -0,0 +1,89 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+)
+
+// maxInspectedConfigSize is the size above which the config blob is not fetched by Inspect.
+const maxInspectedConfigSize = 1 << 20
+
+// Inspection describes an artifact manifest, its config and the artifacts attached to it.
+type Inspection struct {
+	// Descriptor is the descriptor of the manifest.
+	Descriptor ocispec.Descriptor `json:"descriptor"`
+
+	// Manifest is the decoded manifest.
+	Manifest ocispec.Manifest `json:"manifest"`
+
+	// Config is the content of the config blob, when it is a JSON document of at most 1 MiB.
+	Config json.RawMessage `json:"config,omitempty"`
+
+	// Annotations are the manifest annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Referrers are the artifacts attached to the manifest.
+	Referrers []ocispec.Descriptor `json:"referrers,omitempty"`
+
+	// RawManifest is the manifest exactly as served by the repository.
+	RawManifest []byte `json:"-"`
+}
+
+// Inspect fetches the manifest referenced by a tag or digest of a repository, along with its config
+// and referrers. No layer is downloaded. Referrers are listed on a best-effort basis: a repository
+// that cannot list them is reported as having none.
+func (c *Controller) Inspect(repo, reference string) (*Inspection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancel()
+
+	src, err := c.setupSource(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// The manifest is copied to the local store, where its raw content is read back
+	descriptor, err := c.FetchOCIContainerAnnotations(repo, reference)
+	if err != nil {
+		return nil, err
+	}
+	manifestDesc, rawManifest, err := oras.FetchBytes(ctx, c.Store, reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", reference, err)
+	}
+
+	inspection := &Inspection{
+		Descriptor:  manifestDesc,
+		Annotations: descriptor.Annotations,
+		RawManifest: rawManifest,
+	}
+	if err := json.Unmarshal(rawManifest, &inspection.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", reference, err)
+	}
+	inspection.Descriptor.ArtifactType = inspection.Manifest.ArtifactType
+
+	if config := inspection.Manifest.Config; config.Size > 0 && config.Size <= maxInspectedConfigSize {
+		configBytes, err := content.FetchAll(ctx, src, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch config %s: %w", config.Digest, err)
+		}
+		if json.Valid(configBytes) {
+			inspection.Config = configBytes
+		}
+	}
+
+	if graph, ok := src.(content.ReadOnlyGraphStorage); ok {
+		referrers, err := registry.Referrers(ctx, graph, manifestDesc, "")
+		if err != nil {
+			c.Logger.Warn("Failed to list referrers", "repo", repo, "reference", reference, "error", err)
+		}
+		inspection.Referrers = referrers
+	}
+
+	return inspection, nil
+}
@@ -0,0 +1,67 @@
+package oci
+
+import (
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// TestInspect verifies that the manifest, annotations and referrers of a tag are reported.
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	layer, err := oras.PushBytes(ctx, store, "text/plain", []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		Layers:              []ocispec.Descriptor{layer},
+		ManifestAnnotations: map[string]string{ocispec.AnnotationRevision: "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	if err := store.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatalf("failed to tag manifest: %v", err)
+	}
+	referrer, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.report", oras.PackManifestOptions{
+		Subject: &manifest,
+	})
+	if err != nil {
+		t.Fatalf("failed to pack referrer: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.Source = store
+
+	inspection, err := controller.Inspect("org/repo", "v1")
+	if err != nil {
+		t.Fatalf("failed to inspect tag: %v", err)
+	}
+	if inspection.Descriptor.Digest != manifest.Digest || len(inspection.RawManifest) != int(manifest.Size) {
+		t.Errorf("unexpected manifest descriptor %+v", inspection.Descriptor)
+	}
+	if len(inspection.Manifest.Layers) != 1 || inspection.Manifest.Layers[0].Digest != layer.Digest {
+		t.Errorf("unexpected layers %+v", inspection.Manifest.Layers)
+	}
+	if inspection.Annotations[ocispec.AnnotationRevision] != "abc123" {
+		t.Errorf("unexpected annotations %v", inspection.Annotations)
+	}
+	if string(inspection.Config) != "{}" {
+		t.Errorf("expected the empty config, got %s", inspection.Config)
+	}
+	if len(inspection.Referrers) != 1 || inspection.Referrers[0].Digest != referrer.Digest {
+		t.Errorf("expected referrer %s, got %+v", referrer.Digest, inspection.Referrers)
+	}
+
+	if _, err := controller.Inspect("org/repo", "missing"); err == nil {
+		t.Error("expected an error for an unknown tag")
+	}
+}
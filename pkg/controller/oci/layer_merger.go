@@ -0,0 +1,68 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+)
+
+// Policies applied when an appended layer has the same title as a layer of the existing artifact.
+const (
+	// ConflictFail rejects the append.
+	ConflictFail = "fail"
+
+	// ConflictReplace replaces the existing layer, keeping its position.
+	ConflictReplace = "replace"
+
+	// ConflictSkip keeps the existing layer and drops the appended one.
+	ConflictSkip = "skip"
+)
+
+// MergeLayers appends the new layers to the existing ones, applying the conflict policy to
+// new layers whose title matches an existing layer. Replaced layers keep their position.
+func MergeLayers(existing, added []ocispec.Descriptor, onConflict string, logger *slog.Logger) ([]ocispec.Descriptor, error) {
+	layers := append([]ocispec.Descriptor(nil), existing...)
+	titles := make(map[string]int, len(existing))
+	for i, layer := range existing {
+		if title := layer.Annotations[ocispec.AnnotationTitle]; title != "" {
+			titles[title] = i
+		}
+	}
+
+	for _, layer := range added {
+		title := layer.Annotations[ocispec.AnnotationTitle]
+		i, exists := titles[title]
+		if !exists {
+			layers = append(layers, layer)
+			continue
+		}
+
+		switch onConflict {
+		case ConflictReplace:
+			logger.Info("Replacing existing layer", "title", title, "previous", layers[i].Digest.String())
+			layers[i] = layer
+		case ConflictSkip:
+			logger.Info("Skipping file already present in the artifact", "title", title)
+		default:
+			return nil, fmt.Errorf("artifact already contains %q (use --on-conflict replace or skip)", title)
+		}
+	}
+
+	return layers, nil
+}
+
+// EnsureTagUnchanged re-resolves the tag and fails if it no longer points to the manifest the
+// append started from, so that concurrent appends do not silently drop each other's files.
+func EnsureTagUnchanged(ctx context.Context, repo content.Resolver, tag string, expected ocispec.Descriptor) error {
+	current, err := repo.Resolve(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("failed to re-resolve tag %s: %w", tag, err)
+	}
+	if current.Digest != expected.Digest {
+		return fmt.Errorf("tag %s moved from %s to %s while appending, retry the append", tag, expected.Digest, current.Digest)
+	}
+	return nil
+}
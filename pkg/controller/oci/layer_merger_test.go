@@ -0,0 +1,139 @@
+package oci
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// titledLayer returns a layer descriptor titled with name and identified by its content.
+func titledLayer(name, content string) ocispec.Descriptor {
+	desc := ocispec.Descriptor{MediaType: "text/plain", Digest: digest.FromString(content), Size: int64(len(content))}
+	if name != "" {
+		desc.Annotations = map[string]string{ocispec.AnnotationTitle: name}
+	}
+	return desc
+}
+
+// TestMergeLayers verifies the conflict policies applied to appended layers named like existing ones.
+func TestMergeLayers(t *testing.T) {
+	existing := []ocispec.Descriptor{titledLayer("a.txt", "a"), titledLayer("junit.xml", "old"), titledLayer("", "untitled")}
+
+	testCases := []struct {
+		name        string
+		added       []ocispec.Descriptor
+		onConflict  string
+		expected    []ocispec.Descriptor
+		expectedErr string
+	}{
+		{
+			name:       "new files are appended",
+			added:      []ocispec.Descriptor{titledLayer("b.txt", "b"), titledLayer("", "other")},
+			onConflict: ConflictFail,
+			expected:   append(append([]ocispec.Descriptor(nil), existing...), titledLayer("b.txt", "b"), titledLayer("", "other")),
+		},
+		{
+			name:       "replace keeps the position of the existing layer",
+			added:      []ocispec.Descriptor{titledLayer("junit.xml", "new"), titledLayer("b.txt", "b")},
+			onConflict: ConflictReplace,
+			expected:   []ocispec.Descriptor{existing[0], titledLayer("junit.xml", "new"), existing[2], titledLayer("b.txt", "b")},
+		},
+		{
+			name:       "skip keeps the existing layer",
+			added:      []ocispec.Descriptor{titledLayer("junit.xml", "new"), titledLayer("b.txt", "b")},
+			onConflict: ConflictSkip,
+			expected:   append(append([]ocispec.Descriptor(nil), existing...), titledLayer("b.txt", "b")),
+		},
+		{
+			name:        "fail rejects the append",
+			added:       []ocispec.Descriptor{titledLayer("b.txt", "b"), titledLayer("junit.xml", "new")},
+			onConflict:  ConflictFail,
+			expectedErr: `artifact already contains "junit.xml"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			layers, err := MergeLayers(existing, tc.added, tc.onConflict, slog.Default())
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to merge layers: %v", err)
+			}
+
+			if len(layers) != len(tc.expected) {
+				t.Fatalf("expected %d layers, got %d", len(tc.expected), len(layers))
+			}
+			for i := range layers {
+				if layers[i].Digest != tc.expected[i].Digest {
+					t.Errorf("layer %d: expected %s, got %s", i, tc.expected[i].Digest, layers[i].Digest)
+				}
+			}
+		})
+	}
+
+	if existing[1].Digest != digest.FromString("old") {
+		t.Errorf("expected the existing layers to be left untouched")
+	}
+}
+
+// TestEnsureTagUnchanged verifies that a tag moved while appending is detected.
+func TestEnsureTagUnchanged(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	base, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		ManifestAnnotations: map[string]string{"run": "1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	other, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		ManifestAnnotations: map[string]string{"run": "2"},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		tag         string
+		tagged      *ocispec.Descriptor
+		expectedErr string
+	}{
+		{name: "unchanged tag", tag: "unchanged", tagged: &base},
+		{name: "tag moved by a concurrent append", tag: "moved", tagged: &other, expectedErr: "moved from " + base.Digest.String() + " to " + other.Digest.String()},
+		{name: "tag deleted", tag: "deleted", expectedErr: "failed to re-resolve tag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.tagged != nil {
+				if err := store.Tag(ctx, *tc.tagged, tc.tag); err != nil {
+					t.Fatalf("failed to tag manifest: %v", err)
+				}
+			}
+
+			err := EnsureTagUnchanged(ctx, store, tc.tag, base)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
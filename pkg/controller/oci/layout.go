@@ -0,0 +1,187 @@
+package oci
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry"
+)
+
+// Schemes of the references to artifacts stored on disk instead of a registry.
+const (
+	// SchemeOCILayout references an OCI image layout directory: oci-layout:/path/to/dir[:tag].
+	SchemeOCILayout = "oci-layout"
+
+	// SchemeOCIArchive references a tar archive of an OCI image layout: oci-archive:/path/file.tar[:tag].
+	SchemeOCIArchive = "oci-archive"
+)
+
+// LocalReference references an artifact in an OCI image layout directory or archive.
+type LocalReference struct {
+	// Scheme is either SchemeOCILayout or SchemeOCIArchive.
+	Scheme string
+
+	// Path is the layout directory or the archive file.
+	Path string
+
+	// Tag is the tag of the artifact in the layout index. Several tags may be separated by commas.
+	Tag string
+}
+
+// ParseLocalReference parses a reference of the form scheme:path[:tag].
+// Returns false when the reference does not use one of the local schemes.
+func ParseLocalReference(reference string) (LocalReference, bool, error) {
+	scheme, rest, found := strings.Cut(reference, ":")
+	if !found || (scheme != SchemeOCILayout && scheme != SchemeOCIArchive) {
+		return LocalReference{}, false, nil
+	}
+
+	ref := LocalReference{Scheme: scheme, Path: rest}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Path, ref.Tag = rest[:i], rest[i+1:]
+	}
+	if ref.Path == "" {
+		return LocalReference{}, true, fmt.Errorf("missing path in %q", reference)
+	}
+	return ref, true, nil
+}
+
+// Name returns the name of the layout, used in place of the repository name: the base name of
+// the directory, or of the archive without its extension.
+func (r LocalReference) Name() string {
+	name := filepath.Base(r.Path)
+	if r.Scheme == SchemeOCIArchive {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// OpenLocalSource opens the layout directory or archive of the reference for reading.
+func OpenLocalSource(ctx context.Context, ref LocalReference) (oras.ReadOnlyTarget, error) {
+	if ref.Scheme == SchemeOCIArchive {
+		store, err := oci.NewFromTar(ctx, ref.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open OCI archive %s: %w", ref.Path, err)
+		}
+		return store, nil
+	}
+
+	store, err := oci.NewFromFS(ctx, os.DirFS(ref.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout %s: %w", ref.Path, err)
+	}
+	return store, nil
+}
+
+// OpenReference configures the controller to read the artifact referenced by a registry reference
+// (quay.io/org/repo:tag or quay.io/org/repo@sha256:...) or by the tag of an OCI image layout or
+// archive, which is opened as the source of the controller and named after its directory or file.
+// Returns the repository and the tag or digest of the artifact.
+func (c *Controller) OpenReference(ctx context.Context, reference string) (string, string, error) {
+	local, isLocal, err := ParseLocalReference(reference)
+	if err != nil {
+		return "", "", err
+	}
+	if isLocal {
+		if local.Tag == "" {
+			return "", "", fmt.Errorf("reference %q must include a tag", reference)
+		}
+		if c.Source, err = OpenLocalSource(ctx, local); err != nil {
+			return "", "", err
+		}
+		return local.Name(), local.Tag, nil
+	}
+
+	ref, err := registry.ParseReference(strings.TrimPrefix(reference, "oci://"))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid reference %q: %w", reference, err)
+	}
+	if ref.Reference == "" {
+		return "", "", fmt.Errorf("reference %q must include a tag or a digest", reference)
+	}
+	c.Registry = ref.Registry
+	return ref.Repository, ref.Reference, nil
+}
+
+// WriteArchive writes the OCI image layout directory to a tar archive readable by OpenLocalSource.
+// Entries are sorted and stripped of timestamps and ownership. An existing archive is replaced.
+func WriteArchive(layoutDir, archivePath string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(archivePath), ".oci-archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive %s: %w", archivePath, err)
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	tw := tar.NewWriter(tmp)
+	err = filepath.WalkDir(layoutDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == layoutDir {
+			return err
+		}
+		rel, err := filepath.Rel(layoutDir, p)
+		if err != nil {
+			return err
+		}
+		// The ingest directory only holds the partial uploads of the layout store.
+		if d.IsDir() && rel == "ingest" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		header.ModTime = time.Unix(0, 0)
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", archivePath, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive %s: %w", archivePath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", archivePath, err)
+	}
+
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return fmt.Errorf("failed to move archive to %s: %w", archivePath, err)
+	}
+	return nil
+}
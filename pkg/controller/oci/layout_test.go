@@ -0,0 +1,66 @@
+package oci
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+)
+
+// TestParseLocalReference verifies the parsing of layout and archive references.
+func TestParseLocalReference(t *testing.T) {
+	cases := map[string]LocalReference{
+		"oci-layout:/tmp/layout:v1":       {Scheme: SchemeOCILayout, Path: "/tmp/layout", Tag: "v1"},
+		"oci-layout:./layout":             {Scheme: SchemeOCILayout, Path: "./layout"},
+		"oci-archive:/media/e2e.tar:a,b":  {Scheme: SchemeOCIArchive, Path: "/media/e2e.tar", Tag: "a,b"},
+		"oci-archive:/media/v1:2/e2e.tar": {Scheme: SchemeOCIArchive, Path: "/media/v1:2/e2e.tar"},
+	}
+	for reference, expected := range cases {
+		ref, isLocal, err := ParseLocalReference(reference)
+		if err != nil || !isLocal || ref != expected {
+			t.Errorf("ParseLocalReference(%q) = %+v, %v, %v; expected %+v", reference, ref, isLocal, err, expected)
+		}
+	}
+
+	if _, isLocal, _ := ParseLocalReference("quay.io/org/repo:tag"); isLocal {
+		t.Errorf("expected a registry reference not to be local")
+	}
+	if ref := (LocalReference{Scheme: SchemeOCIArchive, Path: "/media/e2e.tar"}); ref.Name() != "e2e" {
+		t.Errorf("expected archive name e2e, got %s", ref.Name())
+	}
+}
+
+// TestWriteArchive verifies that an archived layout can be opened and resolved again.
+func TestWriteArchive(t *testing.T) {
+	ctx := context.Background()
+	layoutDir := t.TempDir()
+	store, err := oci.New(layoutDir)
+	if err != nil {
+		t.Fatalf("failed to create layout: %v", err)
+	}
+
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	if err := store.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatalf("failed to tag manifest: %v", err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "e2e.tar")
+	if err := WriteArchive(layoutDir, archive); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	src, err := OpenLocalSource(ctx, LocalReference{Scheme: SchemeOCIArchive, Path: archive})
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	desc, err := src.Resolve(ctx, "v1")
+	if err != nil || desc.Digest != manifest.Digest || desc.MediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("expected v1 to resolve to %s, got %+v (%v)", manifest.Digest, desc, err)
+	}
+}
@@ -0,0 +1,308 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// gzipExpansionRatio estimates how much larger a compressed archive layer gets once extracted.
+	// Test logs and reports compress well, so the estimate errs on the generous side.
+	gzipExpansionRatio = 4
+
+	// planConcurrency limits the number of manifests resolved in parallel while planning.
+	planConcurrency = 10
+)
+
+// LayerPlan describes a single layer that processing a tag would download.
+type LayerPlan struct {
+	// Digest is the digest of the layer blob.
+	Digest string `json:"digest"`
+
+	// MediaType is the media type of the layer.
+	MediaType string `json:"mediaType"`
+
+	// Title is the file or directory name recorded in the layer annotations, if any.
+	Title string `json:"title,omitempty"`
+
+	// Size is the compressed size of the layer in bytes.
+	Size int64 `json:"size"`
+
+	// Cached is true when the blob is already present in the local OCI cache.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// TagPlan describes what processing a single tag would download and extract.
+type TagPlan struct {
+	// Repo is the repository the tag belongs to.
+	Repo string `json:"repo"`
+
+	// Tag is the name of the tag.
+	Tag string `json:"tag"`
+
+	// LastModified is the creation date of the tag used to build the output path.
+	LastModified string `json:"lastModified"`
+
+	// Digest is the digest of the tag manifest.
+	Digest string `json:"digest"`
+
+	// ArtifactType is the artifact type of the manifest, if any.
+	ArtifactType string `json:"artifactType,omitempty"`
+
+	// Layers are the layers of the manifest.
+	Layers []LayerPlan `json:"layers"`
+
+	// OutputDir is the directory the layers would be extracted to.
+	OutputDir string `json:"outputDir"`
+
+	// CompressedSize is the sum of the layer sizes in bytes.
+	CompressedSize int64 `json:"compressedSize"`
+
+	// DownloadSize is the number of bytes missing from the local cache.
+	DownloadSize int64 `json:"downloadSize"`
+
+	// ExtractedSize is the estimated number of bytes written to the output directory.
+	ExtractedSize int64 `json:"estimatedExtractedSize"`
+}
+
+// Plan aggregates the tag plans of a run and their total sizes.
+type Plan struct {
+	// Tags are the planned tags in processing order.
+	Tags []TagPlan `json:"tags"`
+
+	// CompressedSize is the sum of the compressed sizes of all tags.
+	CompressedSize int64 `json:"compressedSize"`
+
+	// DownloadSize is the number of bytes missing from the local cache for all tags.
+	DownloadSize int64 `json:"downloadSize"`
+
+	// ExtractedSize is the estimated number of bytes extracted for all tags.
+	ExtractedSize int64 `json:"estimatedExtractedSize"`
+}
+
+// Add appends a tag plan and accumulates its sizes.
+func (p *Plan) Add(tag TagPlan) {
+	p.Tags = append(p.Tags, tag)
+	p.CompressedSize += tag.CompressedSize
+	p.DownloadSize += tag.DownloadSize
+	p.ExtractedSize += tag.ExtractedSize
+}
+
+// PlanTag resolves the manifest of a tag and describes what processing it would download and extract.
+// Only the manifest is fetched, no layer is downloaded.
+func (c *Controller) PlanTag(repo, tag, creationDate string) (*TagPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancel()
+
+	src, err := c.setupSource(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestDesc, manifest, err := c.fetchManifest(ctx, src, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.planTag(ctx, repo, tag, creationDate, manifestDesc, manifest)
+}
+
+// PlanRepositories lists and filters the tags of every repository and plans each of them.
+// Returns the plan of all tags that could be resolved and the errors encountered for the others.
+func (c *Controller) PlanRepositories(repositories []string) (*Plan, []error) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errors []error
+	)
+	sem := make(chan struct{}, planConcurrency)
+	results := make([][]*TagPlan, len(repositories))
+
+	for i, repo := range repositories {
+		tags, err := c.FetchTags(repo)
+		if err != nil {
+			// Tags of the previous repositories are already being planned
+			mu.Lock()
+			errors = append(errors, fmt.Errorf("repository %s: failed to fetch tags: %w", repo, err))
+			mu.Unlock()
+			continue
+		}
+		tags = c.filterTags(tags)
+		results[i] = make([]*TagPlan, len(tags))
+
+		for j, tagInfo := range tags {
+			wg.Add(1)
+			go func(i, j int, repo string, tagInfo TagInfo) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				tagPlan, err := c.PlanTag(repo, tagInfo.Name, tagInfo.LastModified)
+				if err != nil {
+					mu.Lock()
+					errors = append(errors, fmt.Errorf("repository %s: failed to plan tag %s: %w", repo, tagInfo.Name, err))
+					mu.Unlock()
+					return
+				}
+				results[i][j] = tagPlan
+			}(i, j, repo, tagInfo)
+		}
+	}
+
+	wg.Wait()
+
+	plan := &Plan{}
+	for _, tagPlans := range results {
+		for _, tagPlan := range tagPlans {
+			if tagPlan != nil {
+				plan.Add(*tagPlan)
+			}
+		}
+	}
+
+	return plan, errors
+}
+
+// planTag builds the plan of a tag from its already resolved manifest.
+func (c *Controller) planTag(ctx context.Context, repo, tag, creationDate string, manifestDesc ocispec.Descriptor, manifest ocispec.Manifest) (*TagPlan, error) {
+	tagPlan := &TagPlan{
+		Repo:         repo,
+		Tag:          tag,
+		LastModified: creationDate,
+		Digest:       manifestDesc.Digest.String(),
+		ArtifactType: manifest.ArtifactType,
+		OutputDir:    c.createOutputDirectory(repo, creationDate, tag),
+	}
+	if tagPlan.ArtifactType == "" {
+		tagPlan.ArtifactType = manifest.Config.MediaType
+	}
+
+	for _, layer := range manifest.Layers {
+		cached, err := c.Store.Exists(ctx, layer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check blob %s in the local store: %w", layer.Digest, err)
+		}
+
+		tagPlan.Layers = append(tagPlan.Layers, LayerPlan{
+			Digest:    layer.Digest.String(),
+			MediaType: layer.MediaType,
+			Title:     layer.Annotations[ocispec.AnnotationTitle],
+			Size:      layer.Size,
+			Cached:    cached,
+		})
+		tagPlan.CompressedSize += layer.Size
+		tagPlan.ExtractedSize += estimateExtractedSize(layer)
+		if !cached {
+			tagPlan.DownloadSize += layer.Size
+		}
+	}
+
+	return tagPlan, nil
+}
+
+// estimateExtractedSize estimates the disk usage of a layer once extracted to the output directory.
+func estimateExtractedSize(layer ocispec.Descriptor) int64 {
+	if strings.Contains(layer.MediaType, "gzip") {
+		return layer.Size * gzipExpansionRatio
+	}
+	return layer.Size
+}
+
+// filterTags keeps the tags modified within the Since window of the controller.
+// Tags with an unparsable modification date are kept, since their age is unknown.
+func (c *Controller) filterTags(tags []TagInfo) []TagInfo {
+	if c.Since <= 0 {
+		return tags
+	}
+
+	var filtered []TagInfo
+	for _, tag := range tags {
+		modified, err := ParseTagDate(tag.LastModified)
+		if err == nil && time.Since(modified) > c.Since {
+			continue
+		}
+		filtered = append(filtered, tag)
+	}
+	return filtered
+}
+
+// ParseTagDate parses the last modified date returned by the Quay API.
+func ParseTagDate(date string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC1123Z, date); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC1123, date)
+}
+
+// CheckPlan verifies that a plan fits in the configured size budget and in the free space
+// of the cache and output filesystems, so that a run aborts before it starts instead of
+// failing halfway with ENOSPC.
+func (c *Controller) CheckPlan(plan *Plan) error {
+	if c.MaxTotalSize > 0 && plan.CompressedSize > c.MaxTotalSize {
+		return fmt.Errorf("planned download of %d tags is %s, which exceeds the maximum total size of %s",
+			len(plan.Tags), progress.FormatBytes(plan.CompressedSize), progress.FormatBytes(c.MaxTotalSize))
+	}
+
+	cacheFS, err := statFilesystem(c.OCIStorePath)
+	if err != nil {
+		c.Logger.Warn("Skipping disk space check", "path", c.OCIStorePath, "error", err)
+		return nil
+	}
+	outputFS, err := statFilesystem(c.OutputDir)
+	if err != nil {
+		c.Logger.Warn("Skipping disk space check", "path", c.OutputDir, "error", err)
+		return nil
+	}
+
+	// The cache keeps the compressed blobs while they are extracted, so both are needed at the same time.
+	if cacheFS.id == outputFS.id {
+		return checkFreeSpace(c.OutputDir, plan.DownloadSize+plan.ExtractedSize, outputFS.free)
+	}
+	if err := checkFreeSpace(c.OCIStorePath, plan.DownloadSize, cacheFS.free); err != nil {
+		return err
+	}
+	return checkFreeSpace(c.OutputDir, plan.ExtractedSize, outputFS.free)
+}
+
+// checkFreeSpace returns an error when the required number of bytes exceeds the available space.
+func checkFreeSpace(path string, required int64, available uint64) error {
+	if required > 0 && uint64(required) > available {
+		return fmt.Errorf("not enough disk space on the filesystem of %s: %s required, %s available",
+			path, progress.FormatBytes(required), progress.FormatBytes(int64(available)))
+	}
+	return nil
+}
+
+// filesystem identifies the filesystem holding a path and its free space.
+type filesystem struct {
+	id   uint64
+	free uint64
+}
+
+// statFilesystem returns the filesystem of path, or of its closest existing parent
+// when the directory has not been created yet.
+func statFilesystem(path string) (filesystem, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return filesystem{}, err
+	}
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return statfs(path)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filesystem{}, fmt.Errorf("no existing parent directory for %s", path)
+		}
+		path = parent
+	}
+}
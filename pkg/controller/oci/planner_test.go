@@ -0,0 +1,89 @@
+package oci
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// TestFilterTags verifies that only tags modified within the Since window are kept.
+func TestFilterTags(t *testing.T) {
+	now := time.Now().UTC()
+	tags := []TagInfo{
+		{Name: "recent", LastModified: now.Add(-1 * time.Hour).Format(time.RFC1123Z)},
+		{Name: "old", LastModified: now.Add(-72 * time.Hour).Format(time.RFC1123Z)},
+		{Name: "unknown", LastModified: "not a date"},
+	}
+
+	controller := &Controller{Since: 24 * time.Hour}
+	filtered := controller.filterTags(tags)
+
+	var names []string
+	for _, tag := range filtered {
+		names = append(names, tag.Name)
+	}
+	if strings.Join(names, ",") != "recent,unknown" {
+		t.Errorf("expected tags [recent unknown], got %v", names)
+	}
+}
+
+// TestPlanTag verifies the sizes computed from the manifest layers.
+func TestPlanTag(t *testing.T) {
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+
+	manifest := ocispec.Manifest{
+		ArtifactType: "application/vnd.konflux.test",
+		Layers: []ocispec.Descriptor{
+			{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: digest.FromString("archive"), Size: 100,
+				Annotations: map[string]string{ocispec.AnnotationTitle: "logs"}},
+			{MediaType: "application/xml", Digest: digest.FromString("junit"), Size: 10},
+		},
+	}
+	manifestDesc := ocispec.Descriptor{Digest: digest.FromString("manifest")}
+	date := "Tue, 15 Oct 2024 08:16:38 -0000"
+
+	plan, err := controller.planTag(context.Background(), "org/repo", "v1", date, manifestDesc, manifest)
+	if err != nil {
+		t.Fatalf("failed to plan tag: %v", err)
+	}
+
+	if plan.CompressedSize != 110 || plan.DownloadSize != 110 {
+		t.Errorf("expected compressed and download size 110, got %d and %d", plan.CompressedSize, plan.DownloadSize)
+	}
+	if plan.ExtractedSize != 100*gzipExpansionRatio+10 {
+		t.Errorf("expected extracted size %d, got %d", 100*gzipExpansionRatio+10, plan.ExtractedSize)
+	}
+	if plan.Layers[0].Title != "logs" || plan.ArtifactType != "application/vnd.konflux.test" {
+		t.Errorf("unexpected plan details: %+v", plan)
+	}
+	if !strings.HasSuffix(plan.OutputDir, "org/repo/2024-10-15/v1") {
+		t.Errorf("unexpected output directory %s", plan.OutputDir)
+	}
+}
+
+// TestCheckPlanBudget verifies that plans exceeding the size budget are rejected.
+func TestCheckPlanBudget(t *testing.T) {
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.MaxTotalSize = 1024
+
+	plan := &Plan{}
+	plan.Add(TagPlan{Repo: "org/repo", Tag: "v1", CompressedSize: 512})
+	if err := controller.CheckPlan(plan); err != nil {
+		t.Errorf("unexpected error for a plan within budget: %v", err)
+	}
+
+	plan.Add(TagPlan{Repo: "org/repo", Tag: "v2", CompressedSize: 1024})
+	if err := controller.CheckPlan(plan); err == nil || !strings.Contains(err.Error(), "exceeds the maximum total size") {
+		t.Errorf("expected a budget error, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package oci
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+)
+
+// trackedSource wraps a source target so that the bytes of fetched layers are reported as progress events.
+// Manifests and config blobs are passed through untouched.
+type trackedSource struct {
+	oras.ReadOnlyTarget
+
+	reporter progress.Reporter
+	repo     string
+	tag      string
+	layers   map[string]bool
+
+	// fetched holds the layers already stored and reported before the copy started.
+	mu      sync.Mutex
+	fetched map[string]bool
+}
+
+// newTrackedSource returns a source reporting the transfer of the given manifest layers.
+func (c *Controller) newTrackedSource(src oras.ReadOnlyTarget, repo, tag string, manifest ocispec.Manifest) *trackedSource {
+	layers := make(map[string]bool, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		layers[layer.Digest.String()] = true
+	}
+
+	return &trackedSource{
+		ReadOnlyTarget: src,
+		reporter:       c.Progress,
+		repo:           repo,
+		tag:            tag,
+		layers:         layers,
+		fetched:        make(map[string]bool),
+	}
+}
+
+// markFetched reports a layer stored outside of the copy and excludes it from the copy callbacks.
+func (s *trackedSource) markFetched(event progress.Event) {
+	s.mu.Lock()
+	s.fetched[event.Digest] = true
+	s.mu.Unlock()
+
+	event.Time = time.Now()
+	s.reporter.Report(event)
+}
+
+// reportable returns true for layers of the manifest that were not reported yet.
+func (s *trackedSource) reportable(desc ocispec.Descriptor) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.layers[desc.Digest.String()] && !s.fetched[desc.Digest.String()]
+}
+
+// Fetch fetches the content identified by the descriptor and wraps layer streams with progress reporting.
+func (s *trackedSource) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := s.ReadOnlyTarget.Fetch(ctx, target)
+	if err != nil || !s.layers[target.Digest.String()] {
+		return rc, err
+	}
+	return progress.NewReadCloser(rc, s.reporter, layerEvent(progress.LayerProgress, s.repo, s.tag, target), 0), nil
+}
+
+// copyOptions returns the copy options reporting fetched and cached layers of the tracked source.
+func (s *trackedSource) copyOptions() oras.CopyOptions {
+	opts := oras.DefaultCopyOptions
+	opts.PostCopy = func(ctx context.Context, desc ocispec.Descriptor) error {
+		if s.reportable(desc) {
+			s.reporter.Report(layerEvent(progress.LayerFetched, s.repo, s.tag, desc))
+		}
+		return nil
+	}
+	opts.OnCopySkipped = func(ctx context.Context, desc ocispec.Descriptor) error {
+		if s.reportable(desc) {
+			event := layerEvent(progress.LayerFetched, s.repo, s.tag, desc)
+			event.Cached = true
+			s.reporter.Report(event)
+		}
+		return nil
+	}
+	return opts
+}
+
+// layerEvent builds a progress event of the given type for a layer of a tag.
+func layerEvent(eventType progress.EventType, repo, tag string, layer ocispec.Descriptor) progress.Event {
+	return progress.Event{
+		Type:      eventType,
+		Time:      time.Now(),
+		Repo:      repo,
+		Tag:       tag,
+		Digest:    layer.Digest.String(),
+		MediaType: layer.MediaType,
+		Bytes:     layer.Size,
+		Total:     layer.Size,
+	}
+}
+
+// tagStartedEvent builds the event announcing the download of a tag with the given manifest.
+func tagStartedEvent(repo, tag string, manifestDesc ocispec.Descriptor, manifest ocispec.Manifest) progress.Event {
+	var total int64
+	for _, layer := range manifest.Layers {
+		total += layer.Size
+	}
+
+	return progress.Event{
+		Type:   progress.TagStarted,
+		Time:   time.Now(),
+		Repo:   repo,
+		Tag:    tag,
+		Digest: manifestDesc.Digest.String(),
+		Layers: len(manifest.Layers),
+		Total:  total,
+	}
+}
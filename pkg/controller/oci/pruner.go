@@ -0,0 +1,271 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+)
+
+// DefaultRetention is the age after which tags are pruned when no other retention is configured.
+const DefaultRetention = tagDaysThreshold * 24 * time.Hour
+
+// Deletion modes of Prune.
+const (
+	// PruneTags deletes the tags only. The manifests stay reachable by digest until the
+	// registry garbage collects them.
+	PruneTags = "tag"
+
+	// PruneManifests deletes the manifests, and with them every tag that references them.
+	PruneManifests = "manifest"
+)
+
+// RetentionPolicy selects the tags of a repository to delete. A tag is deleted when it is older
+// than OlderThan and none of the keep rules applies to it.
+type RetentionPolicy struct {
+	// OlderThan is the age after which a tag is deleted. Zero deletes every tag not kept by another rule.
+	OlderThan time.Duration
+
+	// KeepLast keeps the given number of most recently modified tags.
+	KeepLast int
+
+	// KeepMatch keeps the tags whose name matches the expression.
+	KeepMatch *regexp.Regexp
+
+	// KeepAnnotations keeps the tags whose manifest has one of the annotations, given either as a key
+	// or as key=value.
+	KeepAnnotations []string
+}
+
+// PruneDecision records whether a tag is deleted and why.
+type PruneDecision struct {
+	// Tag is the name of the tag.
+	Tag string `json:"tag"`
+
+	// LastModified is the time the tag was last modified, zero when it is unknown.
+	LastModified time.Time `json:"lastModified"`
+
+	// Digest is the digest of the tag manifest, empty when it could not be resolved.
+	Digest string `json:"digest,omitempty"`
+
+	// Delete is true when the tag is selected for deletion.
+	Delete bool `json:"delete"`
+
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+
+	// Error is the error of the deletion, empty when it succeeded or was not attempted.
+	Error string `json:"error,omitempty"`
+}
+
+// PlanPrune applies the retention policy to the tags of a repository and returns one decision per
+// tag, the most recently modified first. The manifests are fetched to check the protective
+// annotations and to resolve the digests. Tags whose date or manifest cannot be read are kept, and
+// in PruneManifests mode so are the tags sharing the manifest of a kept tag.
+func (c *Controller) PlanPrune(repo string, tags []TagInfo, policy RetentionPolicy, mode string) []PruneDecision {
+	summaries, errors := c.DescribeTags(repo, tags)
+	for _, err := range errors {
+		c.Logger.Warn("Keeping tag whose manifest cannot be fetched", "error", err)
+	}
+	byName := make(map[string]TagSummary, len(summaries))
+	for _, summary := range summaries {
+		byName[summary.Name] = summary
+	}
+
+	decisions := make([]PruneDecision, 0, len(tags))
+	for _, tag := range tags {
+		decision := PruneDecision{Tag: tag.Name}
+		if modified, err := ParseTagDate(tag.LastModified); err == nil {
+			decision.LastModified = modified
+		}
+		decisions = append(decisions, decision)
+	}
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].LastModified.After(decisions[j].LastModified)
+	})
+
+	for i := range decisions {
+		decision := &decisions[i]
+		summary, resolved := byName[decision.Tag]
+		decision.Digest = summary.Digest
+
+		switch {
+		case policy.KeepMatch != nil && policy.KeepMatch.MatchString(decision.Tag):
+			decision.Reason = fmt.Sprintf("name matches %s", policy.KeepMatch)
+		case i < policy.KeepLast:
+			decision.Reason = fmt.Sprintf("among the %d most recent tags", policy.KeepLast)
+		case decision.LastModified.IsZero():
+			decision.Reason = "unknown modification date"
+		case time.Since(decision.LastModified) <= policy.OlderThan:
+			decision.Reason = fmt.Sprintf("modified within %s", policy.OlderThan)
+		case !resolved:
+			decision.Reason = "manifest cannot be fetched"
+		default:
+			if key := protectiveAnnotation(summary.Annotations, policy.KeepAnnotations); key != "" {
+				decision.Reason = fmt.Sprintf("protected by annotation %s", key)
+			} else {
+				decision.Delete = true
+				decision.Reason = fmt.Sprintf("modified %s ago", time.Since(decision.LastModified).Round(time.Hour))
+			}
+		}
+	}
+
+	if mode == PruneManifests {
+		keepSharedManifests(decisions)
+	}
+	return decisions
+}
+
+// keepSharedManifests keeps the tags that reference the manifest of a kept tag, since deleting the
+// manifest would delete the kept tag as well.
+func keepSharedManifests(decisions []PruneDecision) {
+	kept := make(map[string]string)
+	for _, decision := range decisions {
+		if !decision.Delete && decision.Digest != "" {
+			kept[decision.Digest] = decision.Tag
+		}
+	}
+	for i := range decisions {
+		if tag, ok := kept[decisions[i].Digest]; ok && decisions[i].Delete {
+			decisions[i].Delete = false
+			decisions[i].Reason = fmt.Sprintf("same manifest as the kept tag %s", tag)
+		}
+	}
+}
+
+// protectiveAnnotation returns the first of the keep annotations found in the manifest annotations.
+func protectiveAnnotation(annotations map[string]string, keep []string) string {
+	for _, entry := range keep {
+		key, value, hasValue := strings.Cut(entry, "=")
+		actual, ok := annotations[key]
+		if ok && (!hasValue || actual == value) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// Prune deletes the tags selected by PlanPrune, in the given mode. Tags are deleted through the
+// Quay API with the OAuth token, and manifests through the registry API. A manifest referenced by
+// several selected tags is deleted once. The error of every failed deletion is recorded in its
+// decision, and the number of failures is returned. Each deletion is bounded by blobTimeout, so
+// that a run pruning many tags is only bounded by ctx.
+func (c *Controller) Prune(ctx context.Context, repo string, decisions []PruneDecision, mode, token string) int {
+	target, err := c.setupSource(repo)
+	if err != nil {
+		return markFailed(decisions, err)
+	}
+
+	failures := 0
+	deleted := make(map[string]error)
+	for i := range decisions {
+		decision := &decisions[i]
+		if !decision.Delete {
+			continue
+		}
+
+		if mode == PruneManifests {
+			if _, done := deleted[decision.Digest]; !done {
+				deleted[decision.Digest] = withRequestTimeout(ctx, func(ctx context.Context) error {
+					return c.deleteManifest(ctx, target, decision.Digest)
+				})
+			}
+			err = deleted[decision.Digest]
+		} else {
+			err = withRequestTimeout(ctx, func(ctx context.Context) error {
+				return c.deleteTag(ctx, target, repo, decision.Tag, token)
+			})
+		}
+
+		if err != nil {
+			decision.Error = err.Error()
+			failures++
+			continue
+		}
+		c.Logger.Info("Deleted", "repo", repo, "tag", decision.Tag, "digest", decision.Digest, "mode", mode)
+	}
+	return failures
+}
+
+// withRequestTimeout calls fn with a context derived from ctx and bounded by blobTimeout.
+func withRequestTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, blobTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// markFailed records the same error in every decision selected for deletion and returns their number.
+func markFailed(decisions []PruneDecision, err error) int {
+	failures := 0
+	for i := range decisions {
+		if decisions[i].Delete {
+			decisions[i].Error = err.Error()
+			failures++
+		}
+	}
+	return failures
+}
+
+// deleteManifest deletes the manifest with the given digest from the target.
+func (c *Controller) deleteManifest(ctx context.Context, target oras.ReadOnlyTarget, digest string) error {
+	deleter, ok := target.(content.Deleter)
+	if !ok {
+		return fmt.Errorf("the repository does not support deleting manifests")
+	}
+
+	desc, err := target.Resolve(ctx, digest)
+	if err != nil {
+		return fmt.Errorf("failed to resolve manifest %s: %w", digest, err)
+	}
+	if err := deleter.Delete(ctx, desc); err != nil {
+		return fmt.Errorf("failed to delete manifest %s: %w", digest, err)
+	}
+	return nil
+}
+
+// deleteTag deletes a tag from the target when it supports untagging, such as an OCI image layout,
+// and through the Quay API of the registry otherwise.
+func (c *Controller) deleteTag(ctx context.Context, target oras.ReadOnlyTarget, repo, tag, token string) error {
+	if untagger, ok := target.(interface {
+		Untag(ctx context.Context, reference string) error
+	}); ok {
+		if err := untagger.Untag(ctx, tag); err != nil {
+			return fmt.Errorf("failed to delete tag %s: %w", tag, err)
+		}
+		return nil
+	}
+	return DeleteQuayTag(ctx, c.Registry, repo, tag, token)
+}
+
+// DeleteQuayTag deletes a tag through the Quay API of the registry host, authenticating with an
+// OAuth application token. The manifest stays in the repository.
+func DeleteQuayTag(ctx context.Context, host, repo, tag, token string) error {
+	return deleteTag(ctx, http.DefaultClient, "https://"+host+"/api/v1/repository/", repo, tag, token)
+}
+
+// deleteTag sends the tag deletion request to the given Quay API base URL.
+func deleteTag(ctx context.Context, client *http.Client, apiURL, repo, tag, token string) error {
+	url := fmt.Sprintf("%s%s/tag/%s", apiURL, repo, tag)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create tag deletion request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag %s: %w", tag, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to delete tag %s: %s", tag, resp.Status)
+	}
+	return nil
+}
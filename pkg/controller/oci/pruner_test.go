@@ -0,0 +1,167 @@
+package oci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+)
+
+// pushTaggedArtifact packs an artifact with the given annotations and tags it in the store.
+func pushTaggedArtifact(t *testing.T, store *oci.Store, annotations map[string]string, tags ...string) ocispec.Descriptor {
+	t.Helper()
+	ctx := context.Background()
+
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		ManifestAnnotations: annotations,
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	for _, tag := range tags {
+		if err := store.Tag(ctx, manifest, tag); err != nil {
+			t.Fatalf("failed to tag manifest: %v", err)
+		}
+	}
+	return manifest
+}
+
+// daysAgo formats a date in the past like the Quay API.
+func daysAgo(days int) string {
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC1123Z)
+}
+
+// TestPlanPrune verifies the retention rules and the deletion of the selected tags and manifests.
+func TestPlanPrune(t *testing.T) {
+	store, err := oci.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	pushTaggedArtifact(t, store, map[string]string{"created": "1"}, "recent")
+	pushTaggedArtifact(t, store, map[string]string{"created": "2"}, "newest-old")
+	pushTaggedArtifact(t, store, map[string]string{"created": "3"}, "v1.0")
+	pushTaggedArtifact(t, store, map[string]string{"dev.konflux-ci.keep": "true"}, "pinned")
+	shared := pushTaggedArtifact(t, store, map[string]string{"created": "4"}, "old", "old-alias")
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.Source = store
+
+	tags := []TagInfo{
+		{Name: "old", LastModified: daysAgo(10)},
+		{Name: "recent", LastModified: daysAgo(1)},
+		{Name: "newest-old", LastModified: daysAgo(5)},
+		{Name: "v1.0", LastModified: daysAgo(30)},
+		{Name: "pinned", LastModified: daysAgo(20)},
+		{Name: "old-alias", LastModified: daysAgo(12)},
+		{Name: "undated", LastModified: "yesterday"},
+		{Name: "missing", LastModified: daysAgo(40)},
+	}
+	policy := RetentionPolicy{
+		OlderThan:       DefaultRetention,
+		KeepLast:        2,
+		KeepMatch:       regexp.MustCompile(`^v[0-9]`),
+		KeepAnnotations: []string{"dev.konflux-ci.keep=true"},
+	}
+
+	decisions := controller.PlanPrune("org/repo", tags, policy, PruneTags)
+	expected := []string{"old", "old-alias"}
+	var deleted []string
+	for _, decision := range decisions {
+		if decision.Delete {
+			deleted = append(deleted, decision.Tag)
+		}
+	}
+	if len(deleted) != len(expected) || deleted[0] != expected[0] || deleted[1] != expected[1] {
+		t.Fatalf("expected %v to be deleted, got %v", expected, decisions)
+	}
+	if decisions[0].Tag != "recent" || decisions[1].Tag != "newest-old" {
+		t.Errorf("expected the most recent tags first, got %v", decisions)
+	}
+
+	if failures := controller.Prune(context.Background(), "org/repo", decisions, PruneTags, ""); failures != 0 {
+		t.Fatalf("expected no failure, got %v", decisions)
+	}
+	ctx := context.Background()
+	if _, err := store.Resolve(ctx, "old"); err == nil {
+		t.Error("expected the tag old to be deleted")
+	}
+	if _, err := store.Resolve(ctx, shared.Digest.String()); err != nil {
+		t.Errorf("expected the manifest to stay in tag mode: %v", err)
+	}
+	if _, err := store.Resolve(ctx, "pinned"); err != nil {
+		t.Errorf("expected the tag pinned to be kept: %v", err)
+	}
+}
+
+// TestPlanPruneManifests verifies that a manifest shared with a kept tag is not deleted.
+func TestPlanPruneManifests(t *testing.T) {
+	store, err := oci.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	shared := pushTaggedArtifact(t, store, map[string]string{"created": "1"}, "old", "latest")
+	expired := pushTaggedArtifact(t, store, map[string]string{"created": "2"}, "older")
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.Source = store
+
+	tags := []TagInfo{
+		{Name: "old", LastModified: daysAgo(10)},
+		{Name: "latest", LastModified: daysAgo(1)},
+		{Name: "older", LastModified: daysAgo(11)},
+	}
+	decisions := controller.PlanPrune("org/repo", tags, RetentionPolicy{OlderThan: DefaultRetention}, PruneManifests)
+	for _, decision := range decisions {
+		if decision.Delete != (decision.Tag == "older") {
+			t.Errorf("unexpected decision %+v", decision)
+		}
+	}
+
+	if failures := controller.Prune(context.Background(), "org/repo", decisions, PruneManifests, ""); failures != 0 {
+		t.Fatalf("expected no failure, got %v", decisions)
+	}
+	ctx := context.Background()
+	if exists, _ := store.Exists(ctx, expired); exists {
+		t.Error("expected the expired manifest to be deleted")
+	}
+	if exists, _ := store.Exists(ctx, shared); !exists {
+		t.Error("expected the shared manifest to be kept")
+	}
+}
+
+// TestDeleteTag verifies the request sent to the Quay API.
+func TestDeleteTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/repository/org/repo/tag/v2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/v1/repository/org/repo/tag/v1" ||
+			r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := deleteTag(context.Background(), server.Client(), server.URL+"/api/v1/repository/", "org/repo", "v1", "secret"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := deleteTag(context.Background(), server.Client(), server.URL+"/api/v1/repository/", "org/repo", "v2", "secret"); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
@@ -0,0 +1,87 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Referrers lists the artifacts attached to the subject manifest of a repository, optionally
+// filtered by artifact type. The subject is a tag or a digest. Registries without the Referrers
+// API are served through the referrers tag schema.
+func (c *Controller) Referrers(repo, subject, artifactType string) ([]ocispec.Descriptor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancel()
+
+	repoRemote, err := c.setupRemoteRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectDesc, err := repoRemote.Resolve(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve subject %s in %s: %w", subject, repo, err)
+	}
+
+	var referrers []ocispec.Descriptor
+	err = repoRemote.Referrers(ctx, subjectDesc, artifactType, func(page []ocispec.Descriptor) error {
+		referrers = append(referrers, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referrers of %s in %s: %w", subject, repo, err)
+	}
+
+	return referrers, nil
+}
+
+// ProcessReferrers downloads every artifact attached to the subject manifest, like ProcessTag does
+// for a tag. Each artifact is extracted into a directory named after its digest.
+// Returns the errors encountered for the artifacts that could not be processed.
+func (c *Controller) ProcessReferrers(repo, subject, artifactType string) []error {
+	referrers, err := c.Referrers(repo, subject, artifactType)
+	if err != nil {
+		return []error{err}
+	}
+	c.Logger.Info("Found referrers", "repo", repo, "subject", subject, "artifactType", artifactType, "referrers", len(referrers))
+
+	var errors []error
+	for _, referrer := range referrers {
+		if err := c.ProcessTag(repo, referrer.Digest.String(), referrerCreationDate(referrer)); err != nil {
+			errors = append(errors, fmt.Errorf("referrer %s: %w", referrer.Digest, err))
+		}
+	}
+	return errors
+}
+
+// PlanReferrers plans the artifacts attached to the subject manifest without downloading them.
+func (c *Controller) PlanReferrers(repo, subject, artifactType string) (*Plan, []error) {
+	referrers, err := c.Referrers(repo, subject, artifactType)
+	if err != nil {
+		return &Plan{}, []error{err}
+	}
+
+	plan := &Plan{}
+	var errors []error
+	for _, referrer := range referrers {
+		tagPlan, err := c.PlanTag(repo, referrer.Digest.String(), referrerCreationDate(referrer))
+		if err != nil {
+			errors = append(errors, fmt.Errorf("referrer %s: %w", referrer.Digest, err))
+			continue
+		}
+		plan.Add(*tagPlan)
+	}
+	return plan, errors
+}
+
+// referrerCreationDate returns the creation annotation of a referrer in the date format of the
+// Quay API, falling back to the current time when the annotation is missing or invalid.
+func referrerCreationDate(referrer ocispec.Descriptor) string {
+	created, err := time.Parse(time.RFC3339, referrer.Annotations[ocispec.AnnotationCreated])
+	if err != nil {
+		created = time.Now()
+	}
+	return created.UTC().Format(time.RFC1123)
+}
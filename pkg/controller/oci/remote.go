@@ -0,0 +1,47 @@
+package oci
+
+import (
+	"fmt"
+	"strings"
+
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// authCache holds the authentication schemes and tokens of the registries, shared by the remote
+// repositories so that tokens are reused across repositories and failures can be classified.
+var authCache = auth.NewCache()
+
+// NewRemoteRepository creates a client for the remote repository referenced by reference
+// (e.g., quay.io/org/repo), authenticated with the credentials set with SetCredentialOptions,
+// by default those of the Docker configuration. Registries are accessed anonymously when no
+// credentials can be loaded.
+func NewRemoteRepository(reference string) (*remote.Repository, error) {
+	repoRemote, err := remote.NewRepository(reference)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository reference %s: %w", reference, err)
+	}
+
+	repoRemote.Client = &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      authCache,
+		Credential: credential(),
+	}
+
+	return repoRemote, nil
+}
+
+// ParseSubject parses the reference of the image artifacts are attached to, such as
+// quay.io/org/image@sha256:..., which must include a tag or a digest.
+func ParseSubject(image string) (registry.Reference, error) {
+	ref, err := registry.ParseReference(strings.TrimPrefix(image, "oci://"))
+	if err != nil {
+		return registry.Reference{}, fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	if ref.Reference == "" {
+		return registry.Reference{}, fmt.Errorf("image reference %q must include a tag or a digest", image)
+	}
+	return ref, nil
+}
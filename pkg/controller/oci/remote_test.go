@@ -0,0 +1,35 @@
+package oci
+
+import "testing"
+
+// TestParseSubject verifies that subjects are parsed with their tag or digest, which is required.
+func TestParseSubject(t *testing.T) {
+	testCases := []struct {
+		image       string
+		expected    string
+		expectedErr bool
+	}{
+		{image: "quay.io/org/image:v1", expected: "v1"},
+		{image: "oci://quay.io/org/image@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", expected: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+		{image: "quay.io/org/image", expectedErr: true},
+		{image: "not a reference", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := ParseSubject(tc.image)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse subject: %v", err)
+			}
+			if ref.Registry != "quay.io" || ref.Repository != "org/image" || ref.Reference != tc.expected {
+				t.Errorf("unexpected reference %+v", ref)
+			}
+		})
+	}
+}
@@ -27,10 +27,14 @@ type TagInfo struct {
 type TagResponse struct {
 	// A slice of TagInfo structs representing the tags in the response
 	Tags []TagInfo `json:"tags"`
+
+	// HasAdditional is true when more pages of tags follow this one
+	HasAdditional bool `json:"has_additional"`
 }
 
 // FetchTags fetches tags for a repository from Quay.
-// It paginates through the results, retrieving all available tags for the specified repository.
+// It paginates through the results until the API reports no additional page, retrieving all active tags
+// for the specified repository. Expired and deleted tags of the tag history are not returned.
 func (c *Controller) FetchTags(repo string) ([]TagInfo, error) {
 	var tags []TagInfo
 	page := 1
@@ -47,6 +51,9 @@ func (c *Controller) FetchTags(repo string) ([]TagInfo, error) {
 		}
 
 		tags = append(tags, response.Tags...)
+		if !response.HasAdditional {
+			break
+		}
 		page++
 	}
 
@@ -56,7 +63,7 @@ func (c *Controller) FetchTags(repo string) ([]TagInfo, error) {
 // buildTagsURL constructs the tags API URL for a specific repository and page.
 // It formats the URL with the base URL, repository name, number of tags per page, and the current page number.
 func (c *Controller) buildTagsURL(repo string, page int) string {
-	return fmt.Sprintf("%s%s/tag/?limit=%d&page=%d", quayAPITagsURL, repo, perPageTags, page)
+	return fmt.Sprintf("%s%s/tag/?limit=%d&page=%d&onlyActiveTags=true", quayAPITagsURL, repo, perPageTags, page)
 }
 
 // sendTagsRequest sends a GET request to the provided URL and decodes the response into a TagResponse struct.
@@ -0,0 +1,129 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+)
+
+const (
+	// binaryProbeSize is the number of leading bytes of a file checked for NUL bytes to detect binary files.
+	binaryProbeSize = 8000
+
+	// maxSearchLineSize is the length above which the rest of a file is not searched.
+	maxSearchLineSize = 1 << 20
+)
+
+// SearchMatch is a match of a search in a file of an artifact.
+type SearchMatch struct {
+	// Repo and Tag identify the searched artifact.
+	Repo string `json:"repo"`
+	Tag  string `json:"tag"`
+
+	// File is the slash-separated path of the file in the artifact.
+	File string `json:"file"`
+
+	// Line is the number of the matching line, starting at 1, or zero when the file name matches.
+	Line int `json:"line,omitempty"`
+
+	// Text is the matching line, without its line feed.
+	Text string `json:"text,omitempty"`
+}
+
+// SearchTag matches the pattern against the names and the lines of the files of a tag, calling fn
+// for every match in the order of the layers. Binary files are only matched by name. Layers are
+// downloaded to the local store first, so searching a tag again does not download it again; they
+// are read in place from a local source.
+func (c *Controller) SearchTag(repo, tag string, pattern *regexp.Regexp, fn func(SearchMatch) error) error {
+	manifestCtx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancel()
+
+	src, manifest, err := c.openArtifact(manifestCtx, repo, tag)
+	if err != nil {
+		return err
+	}
+
+	var layers content.Fetcher = c.Store
+	if c.Source != nil {
+		layers = src
+	}
+
+	// Downloading and scanning large layers may take longer than blobTimeout, only an idle
+	// download is aborted
+	ctx := context.Background()
+	src = newIdleSource(src, blobIdleTimeout)
+
+	for _, layer := range manifest.Layers {
+		if c.Source == nil {
+			if err := c.cacheLayer(ctx, src, repo, tag, layer); err != nil {
+				return err
+			}
+		}
+
+		err := c.walkLayer(ctx, layers, layer, func(name string, header *tar.Header, r io.Reader) (bool, error) {
+			if header != nil && header.Typeflag != tar.TypeReg {
+				return false, nil
+			}
+			if pattern.MatchString(name) {
+				if err := fn(SearchMatch{Repo: repo, Tag: tag, File: name}); err != nil {
+					return true, err
+				}
+			}
+			return false, searchLines(r, pattern, func(line int, text string) error {
+				return fn(SearchMatch{Repo: repo, Tag: tag, File: name, Line: line, Text: text})
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to search layer %s of %s:%s: %w", layer.Digest, repo, tag, err)
+		}
+	}
+	return nil
+}
+
+// cacheLayer downloads a layer to the local store unless it is already there.
+func (c *Controller) cacheLayer(ctx context.Context, src content.Fetcher, repo, tag string, layer ocispec.Descriptor) error {
+	exists, err := c.Store.Exists(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("failed to check blob %s in the local store: %w", layer.Digest, err)
+	}
+	if exists {
+		return nil
+	}
+	return c.fetchBlobResumable(ctx, src, layer, layerEvent(progress.LayerProgress, repo, tag, layer))
+}
+
+// searchLines calls fn for every line of r matching the pattern. Files that look binary are not
+// searched, and the search of a file stops at a line longer than maxSearchLineSize.
+func searchLines(r io.Reader, pattern *regexp.Regexp, fn func(line int, text string) error) error {
+	reader := bufio.NewReaderSize(r, binaryProbeSize)
+	probe, err := reader.Peek(binaryProbeSize)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return err
+	}
+	if bytes.IndexByte(probe, 0) >= 0 {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxSearchLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if pattern.Match(scanner.Bytes()) {
+			if err := fn(line, string(bytes.TrimSuffix(scanner.Bytes(), []byte("\r")))); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// TestSearchTag verifies that file names and the lines of file and directory layers are matched.
+func TestSearchTag(t *testing.T) {
+	controller := newFilesController(t)
+
+	var matches []SearchMatch
+	collect := func(match SearchMatch) error {
+		matches = append(matches, match)
+		return nil
+	}
+	if err := controller.SearchTag("org/repo", "v1", regexp.MustCompile(`hello|testsuites|junit`), collect); err != nil {
+		t.Fatalf("failed to search tag: %v", err)
+	}
+
+	expected := []SearchMatch{
+		{Repo: "org/repo", Tag: "v1", File: "a.txt", Line: 1, Text: "hello"},
+		{Repo: "org/repo", Tag: "v1", File: "results/sub/junit.xml"},
+		{Repo: "org/repo", Tag: "v1", File: "results/sub/junit.xml", Line: 1, Text: "<testsuites/>"},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("expected %d matches, got %+v", len(expected), matches)
+	}
+	for i := range expected {
+		if matches[i] != expected[i] {
+			t.Errorf("match %d: expected %+v, got %+v", i, expected[i], matches[i])
+		}
+	}
+}
+
+// TestSearchTagStopsOnError verifies that an error returned by the callback ends the search.
+func TestSearchTagStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := newFilesController(t).SearchTag("org/repo", "v1", regexp.MustCompile(`.`), func(SearchMatch) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single callback, got %d", calls)
+	}
+}
+
+// TestSearchLines verifies line numbering and that binary content is not searched.
+func TestSearchLines(t *testing.T) {
+	pattern := regexp.MustCompile(`panic: `)
+
+	var lines []int
+	text := "ok\r\npanic: runtime error\nok\npanic: again"
+	err := searchLines(strings.NewReader(text), pattern, func(line int, text string) error {
+		if !strings.HasPrefix(text, "panic: ") || strings.HasSuffix(text, "\r") {
+			t.Errorf("unexpected text %q", text)
+		}
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to search lines: %v", err)
+	}
+	if len(lines) != 2 || lines[0] != 2 || lines[1] != 4 {
+		t.Errorf("expected lines 2 and 4, got %v", lines)
+	}
+
+	err = searchLines(strings.NewReader("\x00panic: runtime error\n"), pattern, func(int, string) error {
+		t.Error("binary content should not be searched")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to search binary content: %v", err)
+	}
+}
+
+// TestCacheLayer verifies that a layer is downloaded to the local store once.
+func TestCacheLayer(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+	layer, err := oras.PushBytes(ctx, src, "text/plain", []byte("panic: runtime error"))
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	if err := controller.cacheLayer(ctx, src, "org/repo", "v1", layer); err != nil {
+		t.Fatalf("failed to cache layer: %v", err)
+	}
+	if exists, err := controller.Store.Exists(ctx, layer); err != nil || !exists {
+		t.Fatalf("expected the layer in the local store, exists=%v err=%v", exists, err)
+	}
+
+	// A second call must not fetch from the source again
+	if err := controller.cacheLayer(ctx, memory.New(), "org/repo", "v1", layer); err != nil {
+		t.Errorf("expected the cached layer to be reused, got %v", err)
+	}
+}
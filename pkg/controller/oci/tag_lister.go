@@ -0,0 +1,119 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// TagSummary describes a tag of a repository and the artifact it references.
+type TagSummary struct {
+	// Name is the name of the tag.
+	Name string `json:"name" yaml:"name"`
+
+	// LastModified is the time the tag was last modified, zero when Quay returned an unparsable date.
+	LastModified time.Time `json:"lastModified" yaml:"lastModified"`
+
+	// Digest is the digest of the tag manifest.
+	Digest string `json:"digest" yaml:"digest"`
+
+	// ArtifactType is the artifact type of the manifest, or its config media type.
+	ArtifactType string `json:"artifactType,omitempty" yaml:"artifactType,omitempty"`
+
+	// Size is the sum of the sizes of the config and layers in bytes.
+	Size int64 `json:"size" yaml:"size"`
+
+	// Annotations are the manifest annotations.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// ListTags fetches the tags of a repository modified within the Since window of the controller.
+func (c *Controller) ListTags(repo string) ([]TagInfo, error) {
+	tags, err := c.FetchTags(repo)
+	if err != nil {
+		return nil, err
+	}
+	return c.filterTags(tags), nil
+}
+
+// MatchTags keeps the tags whose name matches the expression, or every tag when it is nil.
+func MatchTags(tags []TagInfo, match *regexp.Regexp) []TagInfo {
+	if match == nil {
+		return tags
+	}
+	var matched []TagInfo
+	for _, tag := range tags {
+		if match.MatchString(tag.Name) {
+			matched = append(matched, tag)
+		}
+	}
+	return matched
+}
+
+// DescribeTags resolves the manifest of every tag and summarizes the artifact it references.
+// Manifests are fetched concurrently and no layer is downloaded. Returns the summaries in the
+// order of the tags, without the tags that could not be resolved, and the errors for those.
+func (c *Controller) DescribeTags(repo string, tags []TagInfo) ([]TagSummary, []error) {
+	repoRemote, err := c.setupSource(repo)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errors []error
+	)
+	sem := make(chan struct{}, planConcurrency)
+	results := make([]*TagSummary, len(tags))
+
+	for i, tagInfo := range tags {
+		wg.Add(1)
+		go func(i int, tagInfo TagInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+			defer cancel()
+
+			manifestDesc, manifest, err := c.fetchManifest(ctx, repoRemote, tagInfo.Name)
+			if err != nil {
+				mu.Lock()
+				errors = append(errors, fmt.Errorf("repository %s: %w", repo, err))
+				mu.Unlock()
+				return
+			}
+
+			summary := &TagSummary{
+				Name:         tagInfo.Name,
+				Digest:       manifestDesc.Digest.String(),
+				ArtifactType: manifest.ArtifactType,
+				Size:         manifest.Config.Size,
+				Annotations:  manifest.Annotations,
+			}
+			if summary.ArtifactType == "" {
+				summary.ArtifactType = manifest.Config.MediaType
+			}
+			if modified, err := ParseTagDate(tagInfo.LastModified); err == nil {
+				summary.LastModified = modified
+			}
+			for _, layer := range manifest.Layers {
+				summary.Size += layer.Size
+			}
+			results[i] = summary
+		}(i, tagInfo)
+	}
+
+	wg.Wait()
+
+	var summaries []TagSummary
+	for _, summary := range results {
+		if summary != nil {
+			summaries = append(summaries, *summary)
+		}
+	}
+	return summaries, errors
+}
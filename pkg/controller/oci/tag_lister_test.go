@@ -0,0 +1,57 @@
+package oci
+
+import (
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+// TestDescribeTags verifies the summaries built from the tag manifests, and that unknown tags are reported.
+func TestDescribeTags(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	layer, err := oras.PushBytes(ctx, store, "application/xml", []byte("<testsuites/>"))
+	if err != nil {
+		t.Fatalf("failed to push layer: %v", err)
+	}
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, "application/vnd.konflux.test", oras.PackManifestOptions{
+		Layers:              []ocispec.Descriptor{layer},
+		ManifestAnnotations: map[string]string{ocispec.AnnotationRevision: "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("failed to pack manifest: %v", err)
+	}
+	if err := store.Tag(ctx, manifest, "v1"); err != nil {
+		t.Fatalf("failed to tag manifest: %v", err)
+	}
+
+	controller, err := NewController(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create controller: %v", err)
+	}
+	controller.Source = store
+
+	summaries, errors := controller.DescribeTags("org/repo", []TagInfo{
+		{Name: "v1", LastModified: "Tue, 15 Oct 2024 08:16:38 -0000"},
+		{Name: "missing"},
+	})
+	if len(errors) != 1 || len(summaries) != 1 {
+		t.Fatalf("expected one summary and one error, got %v and %v", summaries, errors)
+	}
+
+	summary := summaries[0]
+	if summary.Digest != manifest.Digest.String() || summary.ArtifactType != "application/vnd.konflux.test" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	// The empty config of the manifest counts for 2 bytes.
+	if summary.Size != layer.Size+2 {
+		t.Errorf("expected size %d, got %d", layer.Size+2, summary.Size)
+	}
+	if summary.Annotations[ocispec.AnnotationRevision] != "abc123" || summary.LastModified.Year() != 2024 {
+		t.Errorf("unexpected annotations or date in %+v", summary)
+	}
+}
@@ -2,29 +2,40 @@ package oci
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content/oci"
 	"oras.land/oras-go/v2/registry/remote"
-	"oras.land/oras-go/v2/registry/remote/auth"
-	"oras.land/oras-go/v2/registry/remote/credentials"
-	"oras.land/oras-go/v2/registry/remote/retry"
 )
 
 // Constants for configurable settings
 const (
-	blobTimeout      = 2 * time.Minute
+	// blobTimeout bounds the requests for manifests and other metadata of a tag.
+	blobTimeout = 2 * time.Minute
+
+	// blobIdleTimeout aborts the transfer of a blob that receives no byte for this duration.
+	// Blob transfers have no deadline, so that large layers can complete.
+	blobIdleTimeout  = 2 * time.Minute
 	tagDaysThreshold = 4
 )
 
+// ManifestFileSuffix is appended to the output directory of a tag to name the file its manifest is
+// saved to, next to the extracted files rather than among them.
+const ManifestFileSuffix = ".manifest.json"
+
 // Processes individual tags from a given repository
-func (c *Controller) ProcessTag(repo, tag, creationDate string) error {
+func (c *Controller) ProcessTag(repo, tag, creationDate string) (err error) {
 
 	if err := c.validateCreationDate(creationDate); err != nil {
 		return err
@@ -33,61 +44,115 @@ func (c *Controller) ProcessTag(repo, tag, creationDate string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
 	defer cancel()
 
-	repoRemote, err := c.setupRemoteRepository(repo)
+	src, err := c.setupSource(repo)
+	if err != nil {
+		return err
+	}
+
+	manifestDesc, manifest, err := c.fetchManifest(ctx, src, tag)
+	if err != nil {
+		return err
+	}
+
+	logger := c.Logger.With("repo", repo, "tag", tag, "digest", manifestDesc.Digest.String())
+	logger.Info("Processing tag", "layers", len(manifest.Layers))
+
+	tagPlan, err := c.planTag(ctx, repo, tag, creationDate, manifestDesc, manifest)
 	if err != nil {
 		return err
 	}
+	plan := &Plan{}
+	plan.Add(*tagPlan)
+	if err := c.CheckPlan(plan); err != nil {
+		return err
+	}
+
+	c.Progress.Report(tagStartedEvent(repo, tag, manifestDesc, manifest))
+	defer func() {
+		event := progress.Event{Type: progress.TagDone, Time: time.Now(), Repo: repo, Tag: tag, Digest: manifestDesc.Digest.String()}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		c.Progress.Report(event)
+	}()
+
+	// The layers may take longer than blobTimeout to download, only an idle transfer is aborted
+	tracked := c.newTrackedSource(newIdleSource(src, blobIdleTimeout), repo, tag, manifest)
+	if err := c.fetchLayers(context.Background(), tracked, manifest); err != nil {
+		return err
+	}
 
-	if err := c.copyTagManifest(ctx, repoRemote, tag, c.Store); err != nil {
+	// The layers are stored by now, only the manifest and config remain to be copied
+	copyCtx, cancelCopy := context.WithTimeout(context.Background(), blobTimeout)
+	defer cancelCopy()
+	if err := c.copyTagManifest(copyCtx, tracked, tag, c.Store, tracked.copyOptions()); err != nil {
 		return err
 	}
 
-	outputDir := c.createOutputDirectory(repo, creationDate, tag)
+	outputDir := tagPlan.OutputDir
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
 	}
+	if err := writeManifestFile(outputDir, manifest); err != nil {
+		return err
+	}
 
-	return c.processBlobs(outputDir)
+	logger.Debug("Extracting layers", "output", outputDir)
+	return c.processBlobs(logger, repo, tag, manifest, outputDir)
 }
 
-// Validates the creation date of the tag
+// Validates the creation date of the tag and warns when it is older than the default retention
 func (c *Controller) validateCreationDate(creationDate string) error {
 	parsedDate, err := time.Parse(time.RFC1123, creationDate)
 	if err != nil {
 		return fmt.Errorf("failed to parse creation date %s: %w", creationDate, err)
 	}
 
-	if time.Since(parsedDate) > time.Duration(tagDaysThreshold)*24*time.Hour {
-		return nil
+	// Old tags are still processed, but the prune command deletes them by default
+	if age := time.Since(parsedDate); age > DefaultRetention {
+		c.Logger.Warn("Tag is older than the default retention and may be pruned", "created", creationDate,
+			"age", age.Round(time.Hour), "retention", DefaultRetention)
 	}
 
 	return nil
 }
 
+// Sets up the source of the given repo name: the configured Source, or the remote repository
+func (c *Controller) setupSource(repo string) (oras.ReadOnlyTarget, error) {
+	if c.Source != nil {
+		return c.Source, nil
+	}
+	return c.setupRemoteRepository(repo)
+}
+
 // Sets up the remote repository for the given repo name
 func (c *Controller) setupRemoteRepository(repo string) (*remote.Repository, error) {
-	repoRemote, err := remote.NewRepository("quay.io/" + repo)
+	repoRemote, err := NewRemoteRepository(c.Registry + "/" + repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up remote repository %s: %w", repo, err)
 	}
 
-	credStore, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+	return repoRemote, nil
+}
+
+// Fetches and decodes the image manifest referenced by the tag without downloading its layers
+func (c *Controller) fetchManifest(ctx context.Context, src oras.ReadOnlyTarget, tag string) (ocispec.Descriptor, ocispec.Manifest, error) {
+	desc, manifestBytes, err := oras.FetchBytes(ctx, src, tag, oras.DefaultFetchBytesOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create credential store: %w", err)
+		return ocispec.Descriptor{}, ocispec.Manifest{}, fmt.Errorf("failed to fetch manifest for tag %s: %w", tag, err)
 	}
 
-	repoRemote.Client = &auth.Client{
-		Client:     retry.DefaultClient,
-		Cache:      auth.NewCache(),
-		Credential: credentials.Credential(credStore),
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return ocispec.Descriptor{}, ocispec.Manifest{}, fmt.Errorf("failed to decode manifest for tag %s: %w", tag, err)
 	}
 
-	return repoRemote, nil
+	return desc, manifest, nil
 }
 
-// Copies the tag manifest from the remote repository to the local OCI store
-func (c *Controller) copyTagManifest(ctx context.Context, repoRemote *remote.Repository, tag string, store *oci.Store) error {
-	if _, err := oras.Copy(ctx, repoRemote, tag, store, tag, oras.DefaultCopyOptions); err != nil {
+// Copies the tag manifest from the source repository to the local OCI store
+func (c *Controller) copyTagManifest(ctx context.Context, src oras.ReadOnlyTarget, tag string, store *oci.Store, opts oras.CopyOptions) error {
+	if _, err := oras.Copy(ctx, src, tag, store, tag, opts); err != nil {
 		return fmt.Errorf("failed to copy manifest for tag %s: %w", tag, err)
 	}
 	return nil
@@ -96,34 +161,46 @@ func (c *Controller) copyTagManifest(ctx context.Context, repoRemote *remote.Rep
 // Creates the output directory for the blobs
 func (c *Controller) createOutputDirectory(repo, creationDate, tag string) string {
 	parsedDate, _ := time.Parse(time.RFC1123, creationDate)
-	return filepath.Join(c.OutputDir, repo, parsedDate.Format("2006-01-02"), tag)
+	// Referrers are processed by digest, whose colon is replaced to keep the path portable.
+	return filepath.Join(c.OutputDir, repo, parsedDate.Format("2006-01-02"), strings.ReplaceAll(tag, ":", "-"))
 }
 
-// Processes the blobs by handling individual blob files
-func (c *Controller) processBlobs(outputDir string) error {
-	var wg sync.WaitGroup
-	errors := make(chan error, 10)
-	sem := make(chan struct{}, 10)
-
-	entries, err := os.ReadDir(c.BlobDir)
+// Saves the manifest of a tag next to its output directory, so that its annotations remain
+// available once the artifact is extracted
+func writeManifestFile(outputDir string, manifest ocispec.Manifest) error {
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to read blob directory: %w", err)
+		return fmt.Errorf("failed to encode manifest: %w", err)
 	}
+	if err := os.WriteFile(outputDir+ManifestFileSuffix, manifestBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest of %s: %w", outputDir, err)
+	}
+	return nil
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
+// Processes the layers of the manifest by handling their blob files in the local store.
+// The errors of all the layers that failed to be extracted are returned together.
+func (c *Controller) processBlobs(logger *slog.Logger, repo, tag string, manifest ocispec.Manifest, outputDir string) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(manifest.Layers))
+	sem := make(chan struct{}, 10)
+
+	for _, layer := range manifest.Layers {
 		wg.Add(1)
-		go c.HandleBlob(filepath.Join(c.BlobDir, entry.Name()), outputDir, &wg, errors, sem)
+		event := layerEvent(progress.LayerExtracted, repo, tag, layer)
+		go c.HandleBlob(c.blobPath(layer), outputDir, layer, event, &wg, errs, sem)
 	}
 
 	wg.Wait()
-	close(errors)
+	close(errs)
 
-	for err := range errors {
-		log.Println("Error:", err)
+	var all []error
+	for err := range errs {
+		logger.Error("Failed to process layer", "error", err)
+		all = append(all, err)
+	}
+	if len(all) > 0 {
+		return fmt.Errorf("failed to extract %d of %d layers: %w", len(all), len(manifest.Layers), errors.Join(all...))
 	}
-
 	return nil
 }
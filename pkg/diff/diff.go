@@ -0,0 +1,183 @@
+// Package diff compares two artifacts: the annotations and layers of their manifests, and the
+// files they are extracted to.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Statuses of the changes between two artifacts.
+const (
+	StatusAdded    = "added"
+	StatusRemoved  = "removed"
+	StatusModified = "modified"
+)
+
+// AnnotationChange is an annotation added, removed or modified between two manifests.
+type AnnotationChange struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// LayerChange is a layer present in only one of two manifests.
+type LayerChange struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Title     string `json:"title,omitempty"`
+}
+
+// FileChange is a file added, removed or modified between two extracted artifacts.
+type FileChange struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	OldSize int64  `json:"oldSize,omitempty"`
+	NewSize int64  `json:"newSize,omitempty"`
+	OldHash string `json:"oldHash,omitempty"`
+	NewHash string `json:"newHash,omitempty"`
+
+	// Diff is the unified diff of a modified text file, when requested.
+	Diff string `json:"diff,omitempty"`
+}
+
+// CompareAnnotations returns the annotations that differ between two manifests, sorted by key.
+func CompareAnnotations(old, new map[string]string) []AnnotationChange {
+	var changes []AnnotationChange
+	for key, oldValue := range old {
+		newValue, ok := new[key]
+		switch {
+		case !ok:
+			changes = append(changes, AnnotationChange{Key: key, Status: StatusRemoved, Old: oldValue})
+		case newValue != oldValue:
+			changes = append(changes, AnnotationChange{Key: key, Status: StatusModified, Old: oldValue, New: newValue})
+		}
+	}
+	for key, newValue := range new {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, AnnotationChange{Key: key, Status: StatusAdded, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// CompareLayers returns the layers removed from the old manifest and added to the new one,
+// identified by digest. Layers present in both manifests are not reported.
+func CompareLayers(old, new []ocispec.Descriptor) []LayerChange {
+	oldDigests := make(map[string]bool, len(old))
+	for _, layer := range old {
+		oldDigests[layer.Digest.String()] = true
+	}
+	newDigests := make(map[string]bool, len(new))
+	for _, layer := range new {
+		newDigests[layer.Digest.String()] = true
+	}
+
+	var changes []LayerChange
+	for _, layer := range old {
+		if !newDigests[layer.Digest.String()] {
+			changes = append(changes, layerChange(StatusRemoved, layer))
+		}
+	}
+	for _, layer := range new {
+		if !oldDigests[layer.Digest.String()] {
+			changes = append(changes, layerChange(StatusAdded, layer))
+		}
+	}
+	return changes
+}
+
+// layerChange describes a layer with the given status.
+func layerChange(status string, layer ocispec.Descriptor) LayerChange {
+	return LayerChange{
+		Status:    status,
+		Digest:    layer.Digest.String(),
+		MediaType: layer.MediaType,
+		Size:      layer.Size,
+		Title:     layer.Annotations[ocispec.AnnotationTitle],
+	}
+}
+
+// fileInfo is the size and SHA-256 hash of a regular file.
+type fileInfo struct {
+	size int64
+	hash string
+}
+
+// CompareTrees returns the regular files added, removed or modified between two directories,
+// sorted by path. Files are compared by SHA-256 hash; directories and symbolic links are ignored.
+func CompareTrees(oldDir, newDir string) ([]FileChange, error) {
+	oldFiles, err := hashTree(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := hashTree(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for path, oldFile := range oldFiles {
+		newFile, ok := newFiles[path]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: path, Status: StatusRemoved, OldSize: oldFile.size, OldHash: oldFile.hash})
+		case newFile.hash != oldFile.hash:
+			changes = append(changes, FileChange{Path: path, Status: StatusModified,
+				OldSize: oldFile.size, NewSize: newFile.size, OldHash: oldFile.hash, NewHash: newFile.hash})
+		}
+	}
+	for path, newFile := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			changes = append(changes, FileChange{Path: path, Status: StatusAdded, NewSize: newFile.size, NewHash: newFile.hash})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// hashTree returns the size and hash of every regular file below dir, keyed by slash-separated path.
+func hashTree(dir string) (map[string]fileInfo, error) {
+	files := make(map[string]fileInfo)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, file)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileInfo{size: size, hash: "sha256:" + hex.EncodeToString(hash.Sum(nil))}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash the files of %s: %w", dir, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,73 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// writeTree creates the files of a directory tree.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestCompareTrees verifies the added, removed and modified files.
+func TestCompareTrees(t *testing.T) {
+	oldDir := writeTree(t, map[string]string{"same.txt": "same", "logs/run.log": "ok", "removed.txt": "gone"})
+	newDir := writeTree(t, map[string]string{"same.txt": "same", "logs/run.log": "failed", "added.txt": "new"})
+
+	changes, err := CompareTrees(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct{ path, status string }{
+		{"added.txt", StatusAdded},
+		{"logs/run.log", StatusModified},
+		{"removed.txt", StatusRemoved},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.Path != expected[i].path || change.Status != expected[i].status {
+			t.Errorf("expected %s %s, got %+v", expected[i].status, expected[i].path, change)
+		}
+	}
+	if changes[1].OldSize != 2 || changes[1].NewSize != 6 || changes[1].OldHash == changes[1].NewHash {
+		t.Errorf("unexpected sizes or hashes in %+v", changes[1])
+	}
+}
+
+// TestCompareManifests verifies the annotation and layer changes.
+func TestCompareManifests(t *testing.T) {
+	annotations := CompareAnnotations(
+		map[string]string{"kept": "1", "changed": "old", "removed": "x"},
+		map[string]string{"kept": "1", "changed": "new", "added": "y"},
+	)
+	if len(annotations) != 3 || annotations[0].Key != "added" || annotations[1].Status != StatusModified || annotations[2].Status != StatusRemoved {
+		t.Errorf("unexpected annotation changes %+v", annotations)
+	}
+
+	shared := ocispec.Descriptor{Digest: digest.FromString("shared")}
+	old := ocispec.Descriptor{Digest: digest.FromString("old"), Annotations: map[string]string{ocispec.AnnotationTitle: "old.txt"}}
+	added := ocispec.Descriptor{Digest: digest.FromString("new")}
+	layers := CompareLayers([]ocispec.Descriptor{shared, old}, []ocispec.Descriptor{shared, added})
+	if len(layers) != 2 || layers[0].Status != StatusRemoved || layers[0].Title != "old.txt" || layers[1].Digest != added.Digest.String() {
+		t.Errorf("unexpected layer changes %+v", layers)
+	}
+}
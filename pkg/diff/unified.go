@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDiffCells bounds the size of the table of the longest common subsequence, in lines of the
+// old text times lines of the new text once their common prefix and suffix are removed.
+const maxDiffCells = 16 << 20
+
+// ErrTooLarge is returned by Unified when the texts differ on too many lines to be diffed.
+var ErrTooLarge = errors.New("texts are too large to diff")
+
+// edit is a line of a diff: kept, deleted from the old text or inserted from the new one.
+type edit struct {
+	op   byte
+	line string
+}
+
+// IsText reports whether the content looks like text: valid UTF-8 without NUL bytes.
+func IsText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
+
+// Unified returns the unified diff of two texts with the given number of context lines, or an
+// empty string when they are equal. Lines are matched with a longest common subsequence.
+func Unified(oldName, newName string, old, new []byte, context int) (string, error) {
+	edits, err := diffLines(splitLines(string(old)), splitLines(string(new)))
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, hunk := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		out.WriteString(hunk)
+	}
+	return out.String(), nil
+}
+
+// splitLines splits a text into lines without their line feed.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the edits turning the old lines into the new ones.
+func diffLines(old, new []string) ([]edit, error) {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	a, b := old[prefix:len(old)-suffix], new[prefix:len(new)-suffix]
+	if len(a)*len(b) > maxDiffCells {
+		return nil, ErrTooLarge
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]edit, 0, len(old)+len(new))
+	for _, line := range old[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for _, line := range old[len(old)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits, nil
+}
+
+// hunks groups the changed edits with their context lines into unified diff hunks. Changes
+// separated by at most twice the context are merged into the same hunk.
+func hunks(edits []edit, context int) []string {
+	// Line numbers of the old and new texts before every edit
+	oldLines, newLines := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for k, e := range edits {
+		oldLines[k+1], newLines[k+1] = oldLines[k], newLines[k]
+		if e.op != '+' {
+			oldLines[k+1]++
+		}
+		if e.op != '-' {
+			newLines[k+1]++
+		}
+	}
+
+	var result []string
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough
+		last := k
+		for next := k + 1; next < len(edits) && next-last <= 2*context+1; next++ {
+			if edits[next].op != ' ' {
+				last = next
+			}
+		}
+		start, end := max(0, k-context), min(len(edits), last+context+1)
+
+		oldCount, newCount := oldLines[end]-oldLines[start], newLines[end]-newLines[start]
+		oldStart, newStart := oldLines[start], newLines[start]
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+
+		var hunk strings.Builder
+		fmt.Fprintf(&hunk, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, e := range edits[start:end] {
+			hunk.WriteByte(e.op)
+			hunk.WriteString(e.line)
+			hunk.WriteByte('\n')
+		}
+		result = append(result, hunk.String())
+		k = end
+	}
+	return result
+}
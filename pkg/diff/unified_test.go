@@ -0,0 +1,60 @@
+package diff
+
+import "testing"
+
+// TestUnified verifies the hunks against the output of diff -u.
+func TestUnified(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "distant changes",
+			old:  "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n",
+			new:  "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -9,3 +9,4 @@\n i\n j\n k\n+l\n",
+		},
+		{
+			name:     "close changes",
+			old:      "a\nb\nc\nd\n",
+			new:      "A\nb\nc\nD\n",
+			expected: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n-d\n+D\n",
+		},
+		{
+			name:     "emptied",
+			old:      "x\ny\n",
+			new:      "",
+			expected: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := Unified("old", "new", []byte(tc.old), []byte(tc.new), 3)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, tc.expected, got)
+		}
+	}
+}
+
+// TestIsText verifies the detection of binary content.
+func TestIsText(t *testing.T) {
+	if !IsText([]byte("<testsuites/>\n")) {
+		t.Error("expected XML to be text")
+	}
+	if IsText([]byte{0x1F, 0x8B, 0x00}) || IsText([]byte{0xFF, 0xFE}) {
+		t.Error("expected binary content not to be text")
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestNewJSONLogger verifies that records are written as JSON and filtered by level.
+func TestNewJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", FormatJSON)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	logger.Info("ignored", "repo", "org/repo")
+	logger.Warn("kept", "repo", "org/repo", "tag", "v1", "digest", "sha256:abc")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "kept" || record["repo"] != "org/repo" || record["tag"] != "v1" || record["digest"] != "sha256:abc" {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+// TestNewRejectsInvalidOptions verifies that unknown levels and formats are reported as errors.
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  string
+		format string
+	}{
+		{name: "Unknown level", level: "verbose", format: FormatText},
+		{name: "Unknown format", level: "info", format: "xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(&bytes.Buffer{}, tt.level, tt.format); err == nil {
+				t.Errorf("expected an error for level %q and format %q", tt.level, tt.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// jsonReporter writes every event as a single line of JSON (NDJSON).
+type jsonReporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSON returns a Reporter that emits newline-delimited JSON events to w.
+func NewJSON(w io.Writer) Reporter {
+	return &jsonReporter{encoder: json.NewEncoder(w)}
+}
+
+// Report encodes the event on its own line. Encoding errors are ignored,
+// since progress output must never interrupt a download.
+func (r *jsonReporter) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = r.encoder.Encode(event)
+}
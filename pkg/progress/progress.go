@@ -0,0 +1,123 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// EventType identifies the stage of a download that an Event describes.
+type EventType string
+
+// Event types emitted while tags are downloaded and extracted.
+const (
+	// TagStarted is emitted once the manifest of a tag has been resolved.
+	TagStarted EventType = "tag_started"
+
+	// LayerProgress is emitted periodically while the bytes of a layer are fetched.
+	LayerProgress EventType = "layer_progress"
+
+	// LayerFetched is emitted when a layer is fully stored in the local cache.
+	LayerFetched EventType = "layer_fetched"
+
+	// LayerExtracted is emitted when a layer has been extracted to the output directory.
+	LayerExtracted EventType = "layer_extracted"
+
+	// TagDone is emitted when the processing of a tag has finished, successfully or not.
+	TagDone EventType = "tag_done"
+)
+
+// Supported reporting modes for the --progress flag.
+const (
+	ModeAuto = "auto"
+	ModeTTY  = "tty"
+	ModeJSON = "json"
+	ModeNone = "none"
+)
+
+// Event describes a single step in the download of a tag.
+// It is serialized as one JSON object per line in JSON mode.
+type Event struct {
+	// Type is the kind of event.
+	Type EventType `json:"event"`
+
+	// Time is the moment at which the event was emitted.
+	Time time.Time `json:"time"`
+
+	// Repo is the repository the tag belongs to.
+	Repo string `json:"repo,omitempty"`
+
+	// Tag is the tag being processed.
+	Tag string `json:"tag,omitempty"`
+
+	// Digest is the digest of the layer for layer events, or of the manifest for tag events.
+	Digest string `json:"digest,omitempty"`
+
+	// MediaType is the media type of the layer for layer events.
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Layers is the number of layers of the tag for TagStarted events.
+	Layers int `json:"layers,omitempty"`
+
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// Total is the expected number of bytes of the layer or of the whole tag.
+	Total int64 `json:"total,omitempty"`
+
+	// Cached is true when a layer was already present in the local cache and was not fetched.
+	Cached bool `json:"cached,omitempty"`
+
+	// Error holds the failure reason of a TagDone event.
+	Error string `json:"error,omitempty"`
+}
+
+// Reporter receives progress events. Implementations must be safe for concurrent use,
+// since layers are fetched and extracted in parallel.
+type Reporter interface {
+	Report(event Event)
+}
+
+// nopReporter discards every event.
+type nopReporter struct{}
+
+func (nopReporter) Report(Event) {}
+
+// Nop returns a Reporter that discards all events.
+func Nop() Reporter {
+	return nopReporter{}
+}
+
+// New returns the Reporter for the given mode writing to w.
+// In auto mode the TTY reporter is used when w is a terminal and nothing is reported otherwise.
+func New(mode string, w io.Writer) (Reporter, error) {
+	switch mode {
+	case ModeAuto, "":
+		if isTerminal(w) {
+			return NewTTY(w), nil
+		}
+		return Nop(), nil
+	case ModeTTY:
+		return NewTTY(w), nil
+	case ModeJSON:
+		return NewJSON(w), nil
+	case ModeNone:
+		return Nop(), nil
+	default:
+		return nil, fmt.Errorf("unsupported progress mode %q (expected auto, tty, json or none)", mode)
+	}
+}
+
+// isTerminal reports whether w is a character device such as an interactive terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
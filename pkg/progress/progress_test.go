@@ -0,0 +1,78 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder collects reported events for assertions.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// TestJSONReporter verifies that every event is written as a single JSON line.
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewJSON(&buf)
+
+	reporter.Report(Event{Type: TagStarted, Repo: "org/repo", Tag: "v1", Layers: 2, Total: 42})
+	reporter.Report(Event{Type: TagDone, Repo: "org/repo", Tag: "v1"})
+
+	scanner := bufio.NewScanner(&buf)
+	var types []EventType
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to decode event line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, event.Type)
+	}
+
+	if len(types) != 2 || types[0] != TagStarted || types[1] != TagDone {
+		t.Errorf("expected events [%s %s], got %v", TagStarted, TagDone, types)
+	}
+}
+
+// TestReadCloserReportsBytes verifies that the final progress event carries the offset plus all bytes read.
+func TestReadCloserReportsBytes(t *testing.T) {
+	rec := &recorder{}
+	content := strings.Repeat("x", 1024)
+
+	rc := NewReadCloser(io.NopCloser(strings.NewReader(content)), rec, Event{Digest: "sha256:abc", Total: 1124}, 100)
+	if _, err := io.Copy(io.Discard, rc); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+
+	if len(rec.events) == 0 {
+		t.Fatalf("expected at least one progress event")
+	}
+	last := rec.events[len(rec.events)-1]
+	if last.Type != LayerProgress {
+		t.Errorf("expected event type %s, got %s", LayerProgress, last.Type)
+	}
+	if last.Bytes != 1124 {
+		t.Errorf("expected 1124 bytes reported, got %d", last.Bytes)
+	}
+}
+
+// TestNewRejectsUnknownMode verifies that unsupported progress modes are reported as errors.
+func TestNewRejectsUnknownMode(t *testing.T) {
+	if _, err := New("fancy", io.Discard); err == nil {
+		t.Errorf("expected an error for an unsupported mode")
+	}
+	if _, err := New(ModeAuto, &bytes.Buffer{}); err != nil {
+		t.Errorf("unexpected error for auto mode: %v", err)
+	}
+}
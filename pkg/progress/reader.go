@@ -0,0 +1,45 @@
+package progress
+
+import (
+	"io"
+	"time"
+)
+
+// reportInterval limits how often LayerProgress events are emitted for a single stream.
+const reportInterval = 250 * time.Millisecond
+
+// readCloser counts the bytes read from the wrapped stream and reports them as LayerProgress events.
+type readCloser struct {
+	io.ReadCloser
+	reporter Reporter
+	event    Event
+	last     time.Time
+}
+
+// NewReadCloser wraps rc so that every byte read from it is reported through reporter.
+// The event is used as template for the emitted LayerProgress events and offset is the
+// number of bytes already transferred before rc, e.g. when a download is resumed.
+func NewReadCloser(rc io.ReadCloser, reporter Reporter, event Event, offset int64) io.ReadCloser {
+	event.Type = LayerProgress
+	event.Bytes = offset
+	return &readCloser{
+		ReadCloser: rc,
+		reporter:   reporter,
+		event:      event,
+	}
+}
+
+// Read reads from the wrapped stream and emits a progress event at most once per reportInterval,
+// and always when the end of the stream is reached.
+func (r *readCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.event.Bytes += int64(n)
+
+	now := time.Now()
+	if err == io.EOF || now.Sub(r.last) >= reportInterval {
+		r.last = now
+		r.event.Time = now
+		r.reporter.Report(r.event)
+	}
+	return n, err
+}
@@ -0,0 +1,162 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// redrawInterval limits how often the live status line is redrawn.
+const redrawInterval = 100 * time.Millisecond
+
+// clearLine moves the cursor to the beginning of the line and erases it.
+const clearLine = "\r\033[K"
+
+// ttyReporter renders human readable progress on an interactive terminal.
+// Completed steps are printed as permanent lines while the transfer in flight
+// is shown on a single status line that is redrawn in place.
+type ttyReporter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	tags     map[string]*tagState
+	lastDraw time.Time
+}
+
+// tagState tracks the transfer of a single tag.
+type tagState struct {
+	started time.Time
+	total   int64
+	layers  map[string]*layerState
+}
+
+// layerState tracks the transfer of a single layer.
+type layerState struct {
+	started time.Time
+	bytes   int64
+	total   int64
+}
+
+// NewTTY returns a Reporter that renders per-tag and per-layer progress, throughput and ETA to w.
+func NewTTY(w io.Writer) Reporter {
+	return &ttyReporter{
+		w:    w,
+		tags: make(map[string]*tagState),
+	}
+}
+
+// Report updates the state of the tag referenced by the event and renders it.
+func (r *ttyReporter) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := event.Repo + ":" + event.Tag
+	tag := r.tag(key, event.Time)
+
+	switch event.Type {
+	case TagStarted:
+		tag.started = event.Time
+		tag.total = event.Total
+		fmt.Fprintf(r.w, "%s%s  %d layers, %s\n", clearLine, key, event.Layers, FormatBytes(event.Total))
+	case LayerProgress:
+		layer := tag.layer(event)
+		layer.bytes = event.Bytes
+		if event.Time.Sub(r.lastDraw) >= redrawInterval {
+			r.lastDraw = event.Time
+			fmt.Fprintf(r.w, "%s%s  %s  %s/%s  %s  ETA %s", clearLine, key, shortDigest(event.Digest),
+				FormatBytes(tag.bytes()), FormatBytes(tag.total), formatRate(tag.bytes(), event.Time.Sub(tag.started)), tag.eta(event.Time))
+		}
+	case LayerFetched:
+		layer := tag.layer(event)
+		layer.bytes = event.Total
+		if event.Cached {
+			fmt.Fprintf(r.w, "%s  cached     %s  %s\n", clearLine, shortDigest(event.Digest), FormatBytes(event.Total))
+			return
+		}
+		fmt.Fprintf(r.w, "%s  fetched    %s  %s  %s\n", clearLine, shortDigest(event.Digest), FormatBytes(event.Total),
+			formatRate(event.Total, event.Time.Sub(layer.started)))
+	case LayerExtracted:
+		fmt.Fprintf(r.w, "%s  extracted  %s\n", clearLine, shortDigest(event.Digest))
+	case TagDone:
+		delete(r.tags, key)
+		if event.Error != "" {
+			fmt.Fprintf(r.w, "%s%s  failed: %s\n", clearLine, key, event.Error)
+			return
+		}
+		elapsed := event.Time.Sub(tag.started)
+		fmt.Fprintf(r.w, "%s%s  done, %s in %s (%s)\n", clearLine, key, FormatBytes(tag.bytes()),
+			elapsed.Round(time.Millisecond), formatRate(tag.bytes(), elapsed))
+	}
+}
+
+// tag returns the state for the given tag key, creating it when the tag was not announced.
+func (r *ttyReporter) tag(key string, now time.Time) *tagState {
+	tag, ok := r.tags[key]
+	if !ok {
+		tag = &tagState{started: now, layers: make(map[string]*layerState)}
+		r.tags[key] = tag
+	}
+	return tag
+}
+
+// layer returns the state for the layer of the event, creating it on first sight.
+func (t *tagState) layer(event Event) *layerState {
+	layer, ok := t.layers[event.Digest]
+	if !ok {
+		layer = &layerState{started: event.Time, total: event.Total}
+		t.layers[event.Digest] = layer
+	}
+	return layer
+}
+
+// bytes returns the number of bytes transferred for all layers of the tag.
+func (t *tagState) bytes() int64 {
+	var sum int64
+	for _, layer := range t.layers {
+		sum += layer.bytes
+	}
+	return sum
+}
+
+// eta estimates the remaining time of the tag based on the average throughput so far.
+func (t *tagState) eta(now time.Time) string {
+	done := t.bytes()
+	elapsed := now.Sub(t.started)
+	if done <= 0 || elapsed <= 0 || t.total <= done {
+		return "--"
+	}
+	remaining := time.Duration(float64(t.total-done) / float64(done) * float64(elapsed))
+	return remaining.Round(time.Second).String()
+}
+
+// shortDigest shortens a digest to its algorithm and first 12 hex characters.
+func shortDigest(digest string) string {
+	for i := 0; i < len(digest); i++ {
+		if digest[i] == ':' && len(digest) > i+13 {
+			return digest[:i+13]
+		}
+	}
+	return digest
+}
+
+// formatRate formats the throughput of n bytes transferred over elapsed.
+func formatRate(n int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "--/s"
+	}
+	return FormatBytes(int64(float64(n)/elapsed.Seconds())) + "/s"
+}
+
+// FormatBytes formats a byte count using binary units.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
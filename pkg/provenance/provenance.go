@@ -0,0 +1,196 @@
+// Package provenance detects the CI system running the CLI and derives the manifest annotations
+// describing where an artifact comes from: source repository, revision, pipeline run, pull request and job.
+package provenance
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Annotations of the team namespace, complementing the standard OCI annotations.
+const (
+	// AnnotationProvider is the name of the CI systems the provenance was detected from.
+	AnnotationProvider = "dev.konflux-ci.provider"
+
+	// AnnotationPipelineRun is the name or identifier of the pipeline run that produced the artifact.
+	AnnotationPipelineRun = "dev.konflux-ci.pipeline-run"
+
+	// AnnotationPullRequest is the number of the pull request the pipeline run was triggered for.
+	AnnotationPullRequest = "dev.konflux-ci.pull-request"
+
+	// AnnotationJobURL is the URL of the CI job that produced the artifact.
+	AnnotationJobURL = "dev.konflux-ci.job-url"
+)
+
+// Getenv looks up an environment variable, like os.Getenv.
+type Getenv func(key string) string
+
+// Detector derives provenance annotations from the environment of a CI system.
+type Detector struct {
+	// Name identifies the CI system in the provider annotation.
+	Name string
+
+	// Detect returns the annotations of the CI system, or nil when it is not running in it.
+	Detect func(getenv Getenv) map[string]string
+}
+
+// Detectors are the built-in detectors in order of precedence.
+var Detectors = []Detector{
+	{Name: "tekton", Detect: detectTekton},
+	{Name: "job-spec", Detect: detectJobSpec},
+	{Name: "github", Detect: detectGitHub},
+}
+
+// Detect runs the detectors against the environment and merges their annotations.
+// When several detectors provide the same annotation, the first one wins. When at least one
+// detector matched, the provider and the creation date are recorded as well.
+func Detect(getenv Getenv, detectors ...Detector) map[string]string {
+	if len(detectors) == 0 {
+		detectors = Detectors
+	}
+
+	annotations := make(map[string]string)
+	var providers []string
+	for _, detector := range detectors {
+		detected := detector.Detect(getenv)
+		if len(detected) == 0 {
+			continue
+		}
+		providers = append(providers, detector.Name)
+		for k, v := range detected {
+			if _, exists := annotations[k]; !exists && v != "" {
+				annotations[k] = v
+			}
+		}
+	}
+	if len(providers) == 0 {
+		return annotations
+	}
+
+	annotations[AnnotationProvider] = strings.Join(providers, ",")
+	annotations[ocispec.AnnotationCreated] = time.Now().UTC().Format(time.RFC3339)
+	return annotations
+}
+
+// detectTekton reads the parameters that Tekton and Konflux tasks conventionally expose as environment variables.
+func detectTekton(getenv Getenv) map[string]string {
+	pipelineRun := firstEnv(getenv, "PIPELINE_RUN_NAME", "PIPELINERUN_NAME")
+	if pipelineRun == "" {
+		return nil
+	}
+
+	return map[string]string{
+		AnnotationPipelineRun:      pipelineRun,
+		ocispec.AnnotationSource:   firstEnv(getenv, "GIT_URL", "GIT_REPO_URL", "SOURCE_REPO_URL"),
+		ocispec.AnnotationRevision: firstEnv(getenv, "GIT_REVISION", "GIT_COMMIT", "COMMIT_SHA"),
+		AnnotationPullRequest:      firstEnv(getenv, "PULL_REQUEST_NUMBER", "PR_NUMBER"),
+		AnnotationJobURL:           getenv("PIPELINE_RUN_URL"),
+	}
+}
+
+// jobSpec holds the fields of the JOB_SPEC variable used to derive provenance. Both the Konflux
+// integration test format, with a "git" object, and the Prow format, with "refs", are supported.
+type jobSpec struct {
+	Git struct {
+		PullRequestNumber int    `json:"pull_request_number"`
+		GitOrg            string `json:"git_org"`
+		GitRepo           string `json:"git_repo"`
+		CommitSHA         string `json:"commit_sha"`
+		SourceRepoURL     string `json:"source_repo_url"`
+	} `json:"git"`
+
+	Job     string `json:"job"`
+	BuildID string `json:"buildid"`
+	Refs    struct {
+		Org     string `json:"org"`
+		Repo    string `json:"repo"`
+		BaseSHA string `json:"base_sha"`
+		Pulls   []struct {
+			Number int    `json:"number"`
+			SHA    string `json:"sha"`
+		} `json:"pulls"`
+	} `json:"refs"`
+}
+
+// detectJobSpec parses the JOB_SPEC JSON document of Konflux integration tests and Prow jobs.
+func detectJobSpec(getenv Getenv) map[string]string {
+	raw := getenv("JOB_SPEC")
+	if raw == "" {
+		return nil
+	}
+	var spec jobSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return nil
+	}
+
+	annotations := make(map[string]string)
+	switch {
+	case spec.Git.GitOrg != "" || spec.Git.SourceRepoURL != "":
+		annotations[ocispec.AnnotationSource] = spec.Git.SourceRepoURL
+		if spec.Git.GitOrg != "" && spec.Git.GitRepo != "" {
+			annotations[ocispec.AnnotationSource] = "https://github.com/" + spec.Git.GitOrg + "/" + spec.Git.GitRepo
+		}
+		annotations[ocispec.AnnotationRevision] = spec.Git.CommitSHA
+		if spec.Git.PullRequestNumber > 0 {
+			annotations[AnnotationPullRequest] = strconv.Itoa(spec.Git.PullRequestNumber)
+		}
+	case spec.Refs.Org != "":
+		annotations[ocispec.AnnotationSource] = "https://github.com/" + spec.Refs.Org + "/" + spec.Refs.Repo
+		annotations[ocispec.AnnotationRevision] = spec.Refs.BaseSHA
+		if len(spec.Refs.Pulls) > 0 {
+			annotations[ocispec.AnnotationRevision] = spec.Refs.Pulls[0].SHA
+			annotations[AnnotationPullRequest] = strconv.Itoa(spec.Refs.Pulls[0].Number)
+		}
+	}
+	if spec.Job != "" && spec.BuildID != "" {
+		annotations[AnnotationPipelineRun] = spec.Job + "/" + spec.BuildID
+	}
+
+	return annotations
+}
+
+// detectGitHub reads the default environment variables of GitHub Actions.
+func detectGitHub(getenv Getenv) map[string]string {
+	if getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+
+	server := strings.TrimSuffix(getenv("GITHUB_SERVER_URL"), "/")
+	if server == "" {
+		server = "https://github.com"
+	}
+	repository := getenv("GITHUB_REPOSITORY")
+	runID := getenv("GITHUB_RUN_ID")
+
+	annotations := map[string]string{
+		ocispec.AnnotationRevision: getenv("GITHUB_SHA"),
+		AnnotationPipelineRun:      runID,
+	}
+	if repository != "" {
+		annotations[ocispec.AnnotationSource] = server + "/" + repository
+		if runID != "" {
+			annotations[AnnotationJobURL] = server + "/" + repository + "/actions/runs/" + runID
+		}
+	}
+
+	// Pull request workflows run on refs/pull/<number>/merge.
+	if ref := getenv("GITHUB_REF"); strings.HasPrefix(ref, "refs/pull/") {
+		annotations[AnnotationPullRequest] = strings.SplitN(strings.TrimPrefix(ref, "refs/pull/"), "/", 2)[0]
+	}
+
+	return annotations
+}
+
+// firstEnv returns the value of the first set variable among keys.
+func firstEnv(getenv Getenv, keys ...string) string {
+	for _, key := range keys {
+		if value := getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package provenance
+
+import (
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// env returns a Getenv backed by a map.
+func env(vars map[string]string) Getenv {
+	return func(key string) string { return vars[key] }
+}
+
+// TestDetectGitHub verifies the annotations derived from the GitHub Actions environment of a pull request.
+func TestDetectGitHub(t *testing.T) {
+	annotations := Detect(env(map[string]string{
+		"GITHUB_ACTIONS":    "true",
+		"GITHUB_SERVER_URL": "https://github.com",
+		"GITHUB_REPOSITORY": "konflux-ci/e2e-tests",
+		"GITHUB_SHA":        "abc123",
+		"GITHUB_RUN_ID":     "42",
+		"GITHUB_REF":        "refs/pull/7/merge",
+	}))
+
+	expected := map[string]string{
+		ocispec.AnnotationSource:   "https://github.com/konflux-ci/e2e-tests",
+		ocispec.AnnotationRevision: "abc123",
+		AnnotationPullRequest:      "7",
+		AnnotationPipelineRun:      "42",
+		AnnotationJobURL:           "https://github.com/konflux-ci/e2e-tests/actions/runs/42",
+		AnnotationProvider:         "github",
+	}
+	for k, v := range expected {
+		if annotations[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, annotations[k])
+		}
+	}
+	if annotations[ocispec.AnnotationCreated] == "" {
+		t.Errorf("expected the creation date to be set")
+	}
+}
+
+// TestDetectTektonWithJobSpec verifies that Tekton parameters take precedence over the JOB_SPEC document,
+// which fills in the annotations the parameters do not provide.
+func TestDetectTektonWithJobSpec(t *testing.T) {
+	annotations := Detect(env(map[string]string{
+		"PIPELINE_RUN_NAME": "e2e-run-x7k2p",
+		"GIT_REVISION":      "def456",
+		"JOB_SPEC": `{"git":{"pull_request_number":12,"git_org":"konflux-ci","git_repo":"build-service",` +
+			`"commit_sha":"ignored"}}`,
+	}))
+
+	expected := map[string]string{
+		AnnotationPipelineRun:      "e2e-run-x7k2p",
+		ocispec.AnnotationRevision: "def456",
+		ocispec.AnnotationSource:   "https://github.com/konflux-ci/build-service",
+		AnnotationPullRequest:      "12",
+		AnnotationProvider:         "tekton,job-spec",
+	}
+	for k, v := range expected {
+		if annotations[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, annotations[k])
+		}
+	}
+}
+
+// TestDetectProwJobSpec verifies the Prow JOB_SPEC format.
+func TestDetectProwJobSpec(t *testing.T) {
+	annotations := Detect(env(map[string]string{
+		"JOB_SPEC": `{"job":"pull-ci-e2e","buildid":"1001","refs":{"org":"redhat-appstudio","repo":"infra-deployments",` +
+			`"base_sha":"base","pulls":[{"number":3,"sha":"head"}]}}`,
+	}))
+
+	if annotations[ocispec.AnnotationRevision] != "head" || annotations[AnnotationPullRequest] != "3" ||
+		annotations[AnnotationPipelineRun] != "pull-ci-e2e/1001" {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+}
+
+// TestDetectOutsideCI verifies that nothing is detected in a plain environment.
+func TestDetectOutsideCI(t *testing.T) {
+	if annotations := Detect(env(nil)); len(annotations) != 0 {
+		t.Errorf("expected no annotations, got %v", annotations)
+	}
+}
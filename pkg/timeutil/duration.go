@@ -0,0 +1,23 @@
+// Package timeutil parses the time ranges accepted by the --since flags of the CLI.
+package timeutil
+
+import "time"
+
+// ParseDuration parses a duration like time.ParseDuration, with the additional "d" unit for days (e.g., 2d).
+func ParseDuration(since string) (time.Duration, error) {
+	if len(since) > 1 && since[len(since)-1] == 'd' {
+		days := since[:len(since)-1]
+		hours, err := time.ParseDuration(days + "h")
+		if err != nil {
+			return 0, err
+		}
+		return hours * 24, nil
+	}
+
+	// Parse the duration normally for other time units
+	duration, err := time.ParseDuration(since)
+	if err != nil {
+		return 0, err
+	}
+	return duration, nil
+}
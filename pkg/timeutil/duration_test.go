@@ -0,0 +1,25 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseDuration verifies the day unit and the standard units.
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"2d":  48 * time.Hour,
+		"4h":  4 * time.Hour,
+		"10m": 10 * time.Minute,
+	}
+	for value, expected := range cases {
+		got, err := ParseDuration(value)
+		if err != nil || got != expected {
+			t.Errorf("ParseDuration(%q) = %v, %v; expected %v", value, got, err, expected)
+		}
+	}
+
+	if _, err := ParseDuration("xd"); err == nil {
+		t.Errorf("expected an error for an invalid number of days")
+	}
+}
@@ -0,0 +1,296 @@
+package webui
+
+import (
+	"html"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Classes of the highlighted tokens, styled by the page stylesheet.
+const (
+	classComment = "c"
+	classString  = "s"
+	classNumber  = "n"
+	classKeyword = "k"
+	classKey     = "a"
+)
+
+// language describes the lexical rules of a file format well enough to color it.
+type language struct {
+	name         string
+	keywords     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+
+	// multilineQuotes are the quotes whose strings may span several lines.
+	multilineQuotes string
+
+	// keys colors the strings and words followed by a colon, like the keys of YAML and JSON.
+	keys bool
+
+	// markup colors the element names of XML and HTML tags.
+	markup bool
+}
+
+// words returns a set of keywords.
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	langGo = &language{
+		name: "Go",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false iota`),
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"},
+		quotes: "\"'`", multilineQuotes: "`",
+	}
+	langPython = &language{
+		name: "Python",
+		keywords: words(`and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield None True False`),
+		lineComments: []string{"#"}, quotes: "\"'",
+	}
+	langShell = &language{
+		name: "Shell",
+		keywords: words(`if then else elif fi case esac for while until do done in function return exit
+			export local readonly set unset source echo`),
+		lineComments: []string{"#"}, quotes: "\"'",
+	}
+	langJavaScript = &language{
+		name: "JavaScript",
+		keywords: words(`break case catch class const continue default delete do else export extends finally
+			for function if import in instanceof let new return switch this throw try typeof var void while
+			yield async await null undefined true false interface type enum`),
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"},
+		quotes: "\"'`", multilineQuotes: "`",
+	}
+	langYAML = &language{
+		name:         "YAML",
+		keywords:     words(`true false null yes no on off`),
+		lineComments: []string{"#"}, quotes: "\"'", keys: true,
+	}
+	langJSON = &language{
+		name:     "JSON",
+		keywords: words(`true false null`),
+		quotes:   "\"", keys: true,
+	}
+	langMarkup = &language{
+		name:         "XML",
+		blockComment: [2]string{"<!--", "-->"},
+		quotes:       "\"'", markup: true,
+	}
+	langIni = &language{
+		name:         "INI",
+		keywords:     words(`true false`),
+		lineComments: []string{"#", ";"}, quotes: "\"'",
+	}
+)
+
+// languages maps file extensions to their language.
+var languages = map[string]*language{
+	".go":   langGo,
+	".py":   langPython,
+	".sh":   langShell,
+	".bash": langShell,
+	".js":   langJavaScript,
+	".ts":   langJavaScript,
+	".yaml": langYAML,
+	".yml":  langYAML,
+	".json": langJSON,
+	".xml":  langMarkup,
+	".html": langMarkup,
+	".svg":  langMarkup,
+	".toml": langIni,
+	".ini":  langIni,
+	".conf": langIni,
+	".env":  langIni,
+}
+
+// languageOf returns the language of a file from its name, or nil for plain text.
+func languageOf(name string) *language {
+	base := path.Base(name)
+	if base == "Dockerfile" || base == "Makefile" {
+		return langShell
+	}
+	return languages[strings.ToLower(path.Ext(base))]
+}
+
+// Highlight colors the text according to the language of the file name and returns it as HTML,
+// one element per line so that the lines can be numbered. Text in an unknown language is escaped only.
+func Highlight(name, text string) []template.HTML {
+	text = strings.TrimSuffix(text, "\n")
+	lang := languageOf(name)
+	if lang == nil {
+		return escapeLines(text)
+	}
+
+	var out strings.Builder
+	emit := func(class, token string) {
+		if class == "" {
+			out.WriteString(html.EscapeString(token))
+			return
+		}
+		// Tokens spanning several lines are closed and reopened on every line
+		for i, part := range strings.Split(token, "\n") {
+			if i > 0 {
+				out.WriteByte('\n')
+			}
+			if part != "" {
+				out.WriteString(`<span class="` + class + `">` + html.EscapeString(part) + `</span>`)
+			}
+		}
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case lang.blockComment[0] != "" && strings.HasPrefix(rest, lang.blockComment[0]):
+			end := strings.Index(rest[len(lang.blockComment[0]):], lang.blockComment[1])
+			n := len(rest)
+			if end >= 0 {
+				n = len(lang.blockComment[0]) + end + len(lang.blockComment[1])
+			}
+			emit(classComment, rest[:n])
+			i += n
+
+		case hasAnyPrefix(rest, lang.lineComments) && (i == 0 || isSpace(text[i-1]) || rest[0] != '#'):
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			emit(classComment, rest[:n])
+			i += n
+
+		case strings.IndexByte(lang.quotes, rest[0]) >= 0:
+			n := stringLength(rest, strings.IndexByte(lang.multilineQuotes, rest[0]) >= 0)
+			class := classString
+			if lang.keys && followedByColon(rest[n:]) {
+				class = classKey
+			}
+			emit(class, rest[:n])
+			i += n
+
+		case lang.markup && rest[0] == '<' && len(rest) > 1 && (isWordStart(rest[1]) || rest[1] == '/' || rest[1] == '?'):
+			n := 1 + strings.IndexFunc(rest[1:], func(r rune) bool { return !isWordRune(r) && r != '/' && r != '?' && r != ':' && r != '-' })
+			if n == 0 {
+				n = len(rest)
+			}
+			emit(classKeyword, rest[:n])
+			i += n
+
+		case isDigit(rest[0]) && (i == 0 || !isWordByte(text[i-1])):
+			n := strings.IndexFunc(rest, func(r rune) bool { return !isWordRune(r) && r != '.' })
+			if n < 0 {
+				n = len(rest)
+			}
+			emit(classNumber, rest[:n])
+			i += n
+
+		case isWordStart(rest[0]):
+			n := strings.IndexFunc(rest, func(r rune) bool { return !isWordRune(r) && r != '-' })
+			if n < 0 {
+				n = len(rest)
+			}
+			word := rest[:n]
+			switch {
+			case lang.keys && followedByColon(rest[n:]):
+				emit(classKey, word)
+			case lang.keywords[word]:
+				emit(classKeyword, word)
+			default:
+				emit("", word)
+			}
+			i += n
+
+		default:
+			emit("", rest[:1])
+			i++
+		}
+	}
+	return splitHTML(out.String())
+}
+
+// escapeLines escapes the text and splits it into lines.
+func escapeLines(text string) []template.HTML {
+	return splitHTML(html.EscapeString(text))
+}
+
+// splitHTML splits HTML without elements spanning several lines into lines.
+func splitHTML(content string) []template.HTML {
+	lines := strings.Split(content, "\n")
+	result := make([]template.HTML, len(lines))
+	for i, line := range lines {
+		result[i] = template.HTML(line)
+	}
+	return result
+}
+
+// stringLength returns the length of the string literal starting the text, up to its closing
+// quote, the end of the line for single-line strings, or the end of the text.
+func stringLength(text string, multiline bool) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote != '`':
+			i++
+		case text[i] == quote:
+			return i + 1
+		case text[i] == '\n' && !multiline:
+			return i
+		}
+	}
+	return len(text)
+}
+
+// followedByColon reports whether the next character that is not a blank is a colon.
+func followedByColon(text string) bool {
+	trimmed := strings.TrimLeft(text, " \t")
+	return strings.HasPrefix(trimmed, ":")
+}
+
+// hasAnyPrefix reports whether the text starts with one of the prefixes.
+func hasAnyPrefix(text string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(b byte) bool     { return b == ' ' || b == '\t' || b == '\n' }
+func isDigit(b byte) bool     { return b >= '0' && b <= '9' }
+func isWordStart(b byte) bool { return b == '_' || (b|0x20) >= 'a' && (b|0x20) <= 'z' }
+func isWordByte(b byte) bool  { return isWordStart(b) || isDigit(b) }
+func isWordRune(r rune) bool  { return r < 0x80 && isWordByte(byte(r)) }
+
+// logLevelPattern finds the severity of a log line in the common formats: level=error, [ERROR],
+// E1019 klog prefixes, Go panics and test failures.
+var logLevelPattern = regexp.MustCompile(`(?i)\b(?:level[=:]"?)?(panic|fatal|error|err|fail(?:ed|ure)?|warn(?:ing)?|info|debug|trace)\b|^([EWID])\d{4} `)
+
+// LogLevel returns the severity of a log line: error, warn, info, debug, or an empty string when
+// the line has none.
+func LogLevel(line string) string {
+	match := logLevelPattern.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	switch level := strings.ToLower(match[1] + match[2]); {
+	case level == "e" || level == "panic" || level == "fatal" || level == "error" || level == "err" || strings.HasPrefix(level, "fail"):
+		return "error"
+	case level == "w" || strings.HasPrefix(level, "warn"):
+		return "warn"
+	case level == "i" || level == "info":
+		return "info"
+	default:
+		return "debug"
+	}
+}
@@ -0,0 +1,78 @@
+package webui
+
+import (
+	"html/template"
+	"testing"
+)
+
+// TestHighlight verifies the tokens colored in a few languages and that text is escaped.
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		text     string
+		expected []template.HTML
+	}{
+		{
+			name:     "go",
+			file:     "main.go",
+			text:     "func f() { return \"a<b\" } // done\n",
+			expected: []template.HTML{`<span class="k">func</span> f() { <span class="k">return</span> <span class="s">&#34;a&lt;b&#34;</span> } <span class="c">// done</span>`},
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			text: "key: 42 # comment\nflag: true",
+			expected: []template.HTML{
+				`<span class="a">key</span>: <span class="n">42</span> <span class="c"># comment</span>`,
+				`<span class="a">flag</span>: <span class="k">true</span>`,
+			},
+		},
+		{
+			name: "block comment spanning lines",
+			file: "main.go",
+			text: "/* a\nb */ x",
+			expected: []template.HTML{
+				`<span class="c">/* a</span>`,
+				`<span class="c">b */</span> x`,
+			},
+		},
+		{
+			name:     "plain text",
+			file:     "notes.txt",
+			text:     "<b>not bold</b>",
+			expected: []template.HTML{`&lt;b&gt;not bold&lt;/b&gt;`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Highlight(tt.file, tt.text)
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("expected %d lines, got %q", len(tt.expected), lines)
+			}
+			for i := range lines {
+				if lines[i] != tt.expected[i] {
+					t.Errorf("line %d: expected %q, got %q", i+1, tt.expected[i], lines[i])
+				}
+			}
+		})
+	}
+}
+
+// TestLogLevel verifies the severity found in common log formats.
+func TestLogLevel(t *testing.T) {
+	tests := map[string]string{
+		`time=2024-05-02T10:00:00Z level=ERROR msg="failed"`:    "error",
+		`panic: runtime error: index out of range`:              "error",
+		`E0502 10:00:00.000000 1 controller.go:42] sync failed`: "error",
+		`[WARN] retrying`:        "warn",
+		`level=info msg=started`: "info",
+		`plain line`:             "",
+	}
+	for line, expected := range tests {
+		if level := LogLevel(line); level != expected {
+			t.Errorf("LogLevel(%q): expected %q, got %q", line, expected, level)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+// Package webui serves the artifacts extracted by the download command over HTTP: an index of the
+// repositories, dates and tags of the output directory, the annotations of every tag, and pages to
+// browse directories, read text files and logs, and download files.
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+	artifactdiff "github.com/flacatus/oras-puller/pkg/diff"
+	"github.com/flacatus/oras-puller/pkg/progress"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// dateLayout is the layout of the date directories of the output directory.
+	dateLayout = "2006-01-02"
+
+	// maxViewSize is the size above which a file is offered for download instead of being shown.
+	maxViewSize = 4 << 20
+)
+
+// errOutsideRoot is returned for paths resolving outside of the served directory.
+var errOutsideRoot = errors.New("path is outside of the served directory")
+
+// logExtensions are the extensions of the files shown as logs.
+var logExtensions = map[string]bool{".log": true, ".out": true, ".err": true}
+
+// Server serves an artifacts output directory.
+type Server struct {
+	// Root is the output directory of the download command.
+	Root string
+
+	// Logger receives a record for every failed request. It defaults to slog.Default().
+	Logger *slog.Logger
+
+	// realRoot is Root with its symbolic links resolved, the boundary of every served path.
+	realRoot string
+	mux      *http.ServeMux
+}
+
+// Repository is a repository of the output directory and the dates it has artifacts for, newest first.
+type Repository struct {
+	Name  string
+	Dates []Date
+}
+
+// Date is a date directory of a repository and the tags downloaded under it, sorted by name.
+type Date struct {
+	Date string
+	Tags []Tag
+
+	// Path is the slash-separated path of the directory below the root.
+	Path string
+}
+
+// Tag is the output directory of a tag.
+type Tag struct {
+	Name string
+
+	// Path is the slash-separated path of the directory below the root.
+	Path string
+}
+
+// Entry is a file or directory of a browsed directory.
+type Entry struct {
+	Name     string
+	Path     string
+	Dir      bool
+	Size     int64
+	Modified time.Time
+}
+
+// NewServer returns a server for the output directory.
+func NewServer(root string) (*Server, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve artifacts output directory %s: %w", root, err)
+	}
+	info, err := os.Stat(realRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts output directory %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("artifacts output %s is not a directory", root)
+	}
+
+	s := &Server{Root: root, Logger: slog.Default(), realRoot: realRoot, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /browse/{path...}", s.handleBrowse)
+	s.mux.HandleFunc("GET /raw/{path...}", s.handleRaw)
+	return s, nil
+}
+
+// ServeHTTP dispatches a request to the index, browse or raw handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Index scans the output directory for the repo/date/tag layout of the download command. Any
+// directory named after a date is a date directory, whose parent path is the repository name.
+func (s *Server) Index() ([]Repository, error) {
+	byName := make(map[string]*Repository)
+	err := filepath.WalkDir(s.realRoot, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || file == s.realRoot {
+			return err
+		}
+		if _, err := time.Parse(dateLayout, d.Name()); err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.realRoot, file)
+		if err != nil {
+			return err
+		}
+		repoName := filepath.ToSlash(filepath.Dir(rel))
+		if repoName == "." {
+			return fs.SkipDir
+		}
+
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			return err
+		}
+		date := Date{Date: d.Name(), Path: filepath.ToSlash(rel)}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				date.Tags = append(date.Tags, Tag{Name: entry.Name(), Path: filepath.ToSlash(filepath.Join(rel, entry.Name()))})
+			}
+		}
+		if len(date.Tags) > 0 {
+			if byName[repoName] == nil {
+				byName[repoName] = &Repository{Name: repoName}
+			}
+			byName[repoName].Dates = append(byName[repoName].Dates, date)
+		}
+		return fs.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan artifacts output directory: %w", err)
+	}
+
+	repos := make([]Repository, 0, len(byName))
+	for _, repo := range byName {
+		sort.Slice(repo.Dates, func(i, j int) bool { return repo.Dates[i].Date > repo.Dates[j].Date })
+		repos = append(repos, *repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos, nil
+}
+
+// resolve maps the slash-separated path of a request to a file below the root. Symbolic links are
+// resolved, and paths leaving the root through them or through dot-dot elements are rejected.
+func (s *Server) resolve(urlPath string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	file, err := filepath.EvalSymlinks(filepath.Join(s.realRoot, filepath.FromSlash(cleaned)))
+	if err != nil {
+		return "", err
+	}
+	if file != s.realRoot && !strings.HasPrefix(file, s.realRoot+string(filepath.Separator)) {
+		return "", errOutsideRoot
+	}
+	return file, nil
+}
+
+// handleIndex renders the list of repositories, dates and tags.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	repos, err := s.Index()
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	s.render(w, r, "index", map[string]any{"Title": "Artifacts", "Root": s.Root, "Repositories": repos})
+}
+
+// handleBrowse renders a directory listing, or the page of a file.
+func (s *Server) handleBrowse(w http.ResponseWriter, r *http.Request) {
+	urlPath := strings.Trim(path.Clean("/"+r.PathValue("path")), "/")
+	file, err := s.resolve(urlPath)
+	if err != nil {
+		s.fail(w, r, statusOf(err), err)
+		return
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		s.fail(w, r, statusOf(err), err)
+		return
+	}
+
+	data := map[string]any{"Title": urlPath, "Path": urlPath, "Breadcrumbs": breadcrumbs(urlPath)}
+	if info.IsDir() {
+		entries, err := s.listDir(file, urlPath)
+		if err != nil {
+			s.fail(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		data["Entries"] = entries
+		// The output directory of a tag has its manifest saved next to it by the download command
+		if manifest, err := readManifest(file + oci.ManifestFileSuffix); err == nil {
+			data["Manifest"] = manifest
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			s.Logger.Warn("Failed to read tag manifest", "path", urlPath, "error", err)
+		}
+		s.render(w, r, "dir", data)
+		return
+	}
+
+	data["Size"] = progress.FormatBytes(info.Size())
+	data["Modified"] = info.ModTime()
+	if info.Size() > maxViewSize {
+		data["Reason"] = "The file is too large to be shown."
+		s.render(w, r, "binary", data)
+		return
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		s.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if !artifactdiff.IsText(content) {
+		data["Reason"] = "The file is not a text file."
+		s.render(w, r, "binary", data)
+		return
+	}
+
+	if logExtensions[strings.ToLower(path.Ext(urlPath))] {
+		query := r.URL.Query().Get("q")
+		level := r.URL.Query().Get("level")
+		data["Query"], data["Level"] = query, level
+		data["Lines"] = logLines(string(content), query, level)
+		s.render(w, r, "log", data)
+		return
+	}
+
+	if lang := languageOf(urlPath); lang != nil {
+		data["Language"] = lang.name
+	}
+	data["Lines"] = Highlight(urlPath, string(content))
+	s.render(w, r, "file", data)
+}
+
+// handleRaw serves the content of a file, as an attachment when the download parameter is set.
+func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
+	urlPath := strings.Trim(path.Clean("/"+r.PathValue("path")), "/")
+	file, err := s.resolve(urlPath)
+	if err != nil {
+		s.fail(w, r, statusOf(err), err)
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		s.fail(w, r, statusOf(err), err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		s.fail(w, r, http.StatusNotFound, fmt.Errorf("%s is not a file", urlPath))
+		return
+	}
+
+	if r.URL.Query().Has("download") {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(urlPath)))
+	}
+	// Served files come from artifacts, they must not run scripts in the origin of the page
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// listDir returns the entries of a directory, directories first, then sorted by name.
+func (s *Server) listDir(dir, urlPath string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", urlPath, err)
+	}
+
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		entry := Entry{
+			Name:     dirEntry.Name(),
+			Path:     path.Join(urlPath, dirEntry.Name()),
+			Dir:      info.IsDir(),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		}
+		// Symbolic links are listed as what they point to, when it is inside the root
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := s.resolve(entry.Path)
+			if err != nil {
+				continue
+			}
+			if targetInfo, err := os.Stat(target); err == nil {
+				entry.Dir, entry.Size = targetInfo.IsDir(), targetInfo.Size()
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// readManifest decodes the manifest saved by the download command.
+func readManifest(file string) (*ocispec.Manifest, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", file, err)
+	}
+	return &manifest, nil
+}
+
+// logLine is a line of a log page.
+type logLine struct {
+	Number int
+	Text   string
+	Level  string
+}
+
+// logLines splits a log into numbered lines with their severity, keeping the lines containing the
+// query, case insensitively, and those of the given level when they are set.
+func logLines(content, query, level string) []logLine {
+	query = strings.ToLower(query)
+	var lines []logLine
+	for i, text := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if query != "" && !strings.Contains(strings.ToLower(text), query) {
+			continue
+		}
+		line := logLine{Number: i + 1, Text: strings.TrimSuffix(text, "\r"), Level: LogLevel(text)}
+		if level != "" && line.Level != level {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// breadcrumb is a link to an ancestor directory of a browsed path.
+type breadcrumb struct {
+	Name string
+	Path string
+}
+
+// breadcrumbs returns the links to the ancestors of a path, the path itself included.
+func breadcrumbs(urlPath string) []breadcrumb {
+	if urlPath == "" {
+		return nil
+	}
+	var crumbs []breadcrumb
+	parts := strings.Split(urlPath, "/")
+	for i, part := range parts {
+		crumbs = append(crumbs, breadcrumb{Name: part, Path: strings.Join(parts[:i+1], "/")})
+	}
+	return crumbs
+}
+
+// render executes a page template, logging the failures that happen once the response has started.
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.ExecuteTemplate(w, name, data); err != nil {
+		s.Logger.Error("Failed to render page", "path", r.URL.Path, "error", err)
+	}
+}
+
+// fail logs a failed request and writes its status.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		s.Logger.Error("Request failed", "path", r.URL.Path, "error", err)
+	} else {
+		s.Logger.Debug("Request rejected", "path", r.URL.Path, "status", status, "error", err)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, http.StatusText(status)+"\n")
+}
+
+// statusOf returns the HTTP status reporting a path resolution error.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, errOutsideRoot):
+		return http.StatusForbidden
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// pages are the templates of the pages: index, dir, file, log and binary.
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"bytes":  progress.FormatBytes,
+	"time":   func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"inc":    func(i int) int { return i + 1 },
+	"levels": func() []string { return []string{"error", "warn", "info", "debug"} },
+}).Parse(pageTemplates))
@@ -0,0 +1,143 @@
+package webui
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flacatus/oras-puller/pkg/controller/oci"
+)
+
+// newTestServer returns a server for an output directory holding two tags of a repository.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	root := t.TempDir()
+
+	files := map[string]string{
+		"org/repo/2024-05-02/pr-1/results/junit.xml":        `<testsuites><testsuite name="e2e"/></testsuites>`,
+		"org/repo/2024-05-02/pr-1/build.log":                "level=info msg=start\nlevel=error msg=\"panic: runtime error\"\n",
+		"org/repo/2024-05-02/pr-1/binary.bin":               "\x00\x01\x02",
+		"org/repo/2024-05-01/nightly/main.go":               "package main\n",
+		"org/repo/2024-05-02/pr-1" + oci.ManifestFileSuffix: `{"schemaVersion":2,"annotations":{"org.opencontainers.image.revision":"abc123"}}`,
+	}
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A symbolic link leaving the served directory
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "org/repo/2024-05-02/pr-1/escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(root)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// get requests a path and returns the status and body of the response.
+func get(t *testing.T, ts *httptest.Server, path string) (int, string, http.Header) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return resp.StatusCode, string(body), resp.Header
+}
+
+// TestIndex verifies that the repositories, dates and tags of the output directory are listed.
+func TestIndex(t *testing.T) {
+	status, body, _ := get(t, newTestServer(t), "/")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	for _, link := range []string{`href="/browse/org/repo"`, `href="/browse/org/repo/2024-05-02/pr-1"`, `href="/browse/org/repo/2024-05-01/nightly"`} {
+		if !strings.Contains(body, link) {
+			t.Errorf("expected index to contain %s", link)
+		}
+	}
+	if strings.Index(body, "2024-05-02") > strings.Index(body, "2024-05-01") {
+		t.Error("expected the newest date first")
+	}
+}
+
+// TestBrowseTag verifies that the annotations of a tag are shown with its files.
+func TestBrowseTag(t *testing.T) {
+	status, body, _ := get(t, newTestServer(t), "/browse/org/repo/2024-05-02/pr-1")
+	if status != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+	for _, expected := range []string{"org.opencontainers.image.revision", "abc123", `href="/browse/org/repo/2024-05-02/pr-1/results"`, "build.log"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected tag page to contain %s", expected)
+		}
+	}
+}
+
+// TestBrowseFiles verifies the pages of highlighted text files, logs and binary files.
+func TestBrowseFiles(t *testing.T) {
+	ts := newTestServer(t)
+
+	_, body, _ := get(t, ts, "/browse/org/repo/2024-05-02/pr-1/results/junit.xml")
+	if !strings.Contains(body, `<span class="k">&lt;testsuites</span>`) {
+		t.Errorf("expected highlighted XML, got %s", body)
+	}
+
+	_, body, _ = get(t, ts, "/browse/org/repo/2024-05-02/pr-1/build.log?level=error")
+	if !strings.Contains(body, `class="error"`) || strings.Contains(body, "msg=start") {
+		t.Errorf("expected only the error line of the log, got %s", body)
+	}
+
+	_, body, _ = get(t, ts, "/browse/org/repo/2024-05-02/pr-1/binary.bin")
+	if !strings.Contains(body, "not a text file") {
+		t.Errorf("expected binary file page, got %s", body)
+	}
+}
+
+// TestRaw verifies that files are served as is, as attachments on request.
+func TestRaw(t *testing.T) {
+	status, body, header := get(t, newTestServer(t), "/raw/org/repo/2024-05-01/nightly/main.go?download")
+	if status != http.StatusOK || body != "package main\n" {
+		t.Fatalf("unexpected response %d %q", status, body)
+	}
+	if !strings.HasPrefix(header.Get("Content-Disposition"), "attachment") {
+		t.Errorf("expected an attachment, got %q", header.Get("Content-Disposition"))
+	}
+}
+
+// TestPathsOutsideRoot verifies that neither dot-dot elements nor symbolic links leave the root.
+func TestPathsOutsideRoot(t *testing.T) {
+	ts := newTestServer(t)
+
+	if status, _, _ := get(t, ts, "/raw/org/repo/2024-05-02/pr-1/escape"); status != http.StatusForbidden {
+		t.Errorf("expected status 403 for a symbolic link leaving the root, got %d", status)
+	}
+	if status, body, _ := get(t, ts, "/raw/%2e%2e/%2e%2e/etc/passwd"); status == http.StatusOK {
+		t.Errorf("expected dot-dot elements to be rejected, got %q", body)
+	}
+	if status, _, _ := get(t, ts, "/browse/org/missing"); status != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing path, got %d", status)
+	}
+}
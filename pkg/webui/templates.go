@@ -0,0 +1,112 @@
+package webui
+
+// pageTemplates defines the layout shared by the pages and the body of each page.
+const pageTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - konflux-oci-artifacts</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #1f2328; background: #fff; }
+header { padding: .75rem 1.5rem; background: #24292f; color: #fff; }
+header a { color: #fff; text-decoration: none; font-weight: 600; }
+main { padding: 1rem 1.5rem; }
+a { color: #0969da; }
+nav.crumbs { margin-bottom: 1rem; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: .25rem .75rem .25rem 0; vertical-align: top; }
+td.num { text-align: right; }
+.meta { color: #656d76; font-size: .9rem; }
+pre, .code { font-family: ui-monospace, monospace; font-size: .85rem; }
+.code { border: 1px solid #d0d7de; border-radius: 6px; overflow-x: auto; }
+.code table { width: 100%; }
+.code td { padding: 0 .75rem; white-space: pre; }
+.code td.ln { color: #8c959f; text-align: right; user-select: none; width: 1%; }
+.code td.ln a { color: inherit; text-decoration: none; }
+.code tr:target { background: #fff8c5; }
+.c { color: #6e7781; font-style: italic; } .s { color: #0a3069; } .n { color: #0550ae; }
+.k { color: #cf222e; font-weight: 600; } .a { color: #8250df; }
+tr.error { background: #ffebe9; } tr.warn { background: #fff8c5; } tr.debug { color: #656d76; }
+form.filter { margin-bottom: .75rem; }
+</style>
+</head>
+<body>
+<header><a href="/">konflux-oci-artifacts</a></header>
+<main>
+{{if .Breadcrumbs}}<nav class="crumbs"><a href="/">artifacts</a>{{range .Breadcrumbs}} / <a href="/browse/{{.Path}}">{{.Name}}</a>{{end}}</nav>{{end}}
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" .}}
+<h1>Artifacts</h1>
+<p class="meta">Served from {{.Root}}</p>
+{{range .Repositories}}
+<h2><a href="/browse/{{.Name}}">{{.Name}}</a></h2>
+<table>
+{{range .Dates}}<tr><th><a href="/browse/{{.Path}}">{{.Date}}</a></th>
+<td>{{range $i, $tag := .Tags}}{{if $i}}, {{end}}<a href="/browse/{{$tag.Path}}">{{$tag.Name}}</a>{{end}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No downloaded artifacts. Run the download command with --artifacts-output {{.Root}} first.</p>
+{{end}}
+{{template "footer" .}}{{end}}
+
+{{define "dir"}}{{template "header" .}}
+{{with .Manifest}}
+<h2>Annotations</h2>
+{{if .Annotations}}<table>
+{{range $key, $value := .Annotations}}<tr><th>{{$key}}</th><td>{{$value}}</td></tr>
+{{end}}</table>{{else}}<p class="meta">The manifest has no annotations.</p>{{end}}
+{{if .ArtifactType}}<p class="meta">Artifact type: {{.ArtifactType}}</p>{{end}}
+<h2>Files</h2>
+{{end}}
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th><th></th></tr>
+{{range .Entries}}<tr>
+{{if .Dir}}<td><a href="/browse/{{.Path}}">{{.Name}}/</a></td><td></td>
+{{else}}<td><a href="/browse/{{.Path}}">{{.Name}}</a></td><td class="num">{{bytes .Size}}</td>{{end}}
+<td class="meta">{{time .Modified}}</td>
+<td>{{if not .Dir}}<a href="/raw/{{.Path}}?download">download</a>{{end}}</td>
+</tr>
+{{else}}<tr><td colspan="4" class="meta">Empty directory</td></tr>
+{{end}}</table>
+{{template "footer" .}}{{end}}
+
+{{define "fileinfo"}}<p class="meta">{{.Size}}, modified {{time .Modified}}{{with .Language}} · {{.}}{{end}} ·
+<a href="/raw/{{.Path}}">raw</a> · <a href="/raw/{{.Path}}?download">download</a></p>{{end}}
+
+{{define "file"}}{{template "header" .}}
+{{template "fileinfo" .}}
+<div class="code"><table>
+{{range $i, $line := .Lines}}<tr id="L{{inc $i}}"><td class="ln"><a href="#L{{inc $i}}">{{inc $i}}</a></td><td>{{$line}}</td></tr>
+{{end}}</table></div>
+{{template "footer" .}}{{end}}
+
+{{define "log"}}{{template "header" .}}
+{{template "fileinfo" .}}
+<form class="filter" method="get">
+<input type="search" name="q" value="{{.Query}}" placeholder="Filter lines">
+<select name="level">
+<option value="">All levels</option>
+{{range $level := levels}}<option value="{{$level}}"{{if eq $level $.Level}} selected{{end}}>{{$level}}</option>{{end}}
+</select>
+<button type="submit">Filter</button>
+{{if or .Query .Level}}<a href="/browse/{{.Path}}">clear</a>{{end}}
+</form>
+<div class="code"><table>
+{{range .Lines}}<tr id="L{{.Number}}" class="{{.Level}}"><td class="ln"><a href="#L{{.Number}}">{{.Number}}</a></td><td>{{.Text}}</td></tr>
+{{else}}<tr><td class="meta">No matching lines</td></tr>
+{{end}}</table></div>
+{{template "footer" .}}{{end}}
+
+{{define "binary"}}{{template "header" .}}
+{{template "fileinfo" .}}
+<p>{{.Reason}} <a href="/raw/{{.Path}}?download">Download it</a> instead.</p>
+{{template "footer" .}}{{end}}
+`